@@ -2,15 +2,17 @@ package cache
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"air_router/constants"
 	"air_router/db"
 	"air_router/models"
 	"air_router/utils"
@@ -67,8 +69,29 @@ var keyModelCache = []string{
 	"banana",
 }
 
-// StartModelsCacheTask starts a background task to periodically refresh models cache
-func StartModelsCacheTask(accountDB *db.AccountDB, modelDB *db.ModelDB) {
+// RefreshStatus describes the outcome of the last models cache refresh
+type RefreshStatus struct {
+	FinishedAt     time.Time // zero before the first refresh completes
+	Duration       time.Duration
+	Success        bool      // the cache was rebuilt
+	FailedAccounts int       // accounts whose models could not be fetched
+	LoadedAt       time.Time // last refresh that rebuilt the cache from at least one account, or found none enabled; zero until then
+}
+
+var lastRefresh struct {
+	mu     sync.RWMutex
+	status RefreshStatus
+}
+
+// GetLastRefreshStatus returns the outcome of the last models cache refresh
+func GetLastRefreshStatus() RefreshStatus {
+	lastRefresh.mu.RLock()
+	defer lastRefresh.mu.RUnlock()
+	return lastRefresh.status
+}
+
+// StartModelsCacheTask refreshes the models cache now and then periodically, until ctx is canceled
+func StartModelsCacheTask(ctx context.Context, accountDB *db.AccountDB, modelDB *db.ModelDB) {
 	// Initial fetch
 	RefreshModelsCache(accountDB, modelDB)
 
@@ -76,24 +99,43 @@ func StartModelsCacheTask(accountDB *db.AccountDB, modelDB *db.ModelDB) {
 	ticker := time.NewTicker(3 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		RefreshModelsCache(accountDB, modelDB)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("models cache task stopped", "component", "models_cache")
+			return
+		case <-ticker.C:
+			RefreshModelsCache(accountDB, modelDB)
+		}
 	}
 }
 
 // RefreshModelsCache fetches models from all enabled accounts and updates the cache
 func RefreshModelsCache(accountDB *db.AccountDB, modelDB *db.ModelDB) {
-	log.Println("[ModelsCache] Starting cache refresh...")
+	slog.Info("starting models cache refresh", "component", "models_cache")
+
+	start := time.Now()
+	success, failedAccounts, loaded := false, 0, false
+	defer func() {
+		lastRefresh.mu.Lock()
+		loadedAt := lastRefresh.status.LoadedAt
+		if loaded {
+			loadedAt = time.Now()
+		}
+		lastRefresh.status = RefreshStatus{FinishedAt: time.Now(), Duration: time.Since(start), Success: success, FailedAccounts: failedAccounts, LoadedAt: loadedAt}
+		lastRefresh.mu.Unlock()
+	}()
 
 	// Get all enabled accounts
 	accounts, err := accountDB.GetEnabledAccounts()
 	if err != nil {
-		log.Printf("[ModelsCache] Error getting accounts: %v", err)
+		slog.Error("error getting accounts", "component", "models_cache", "error", err)
 		return
 	}
 
 	if len(accounts) == 0 {
-		log.Println("[ModelsCache] No enabled accounts found")
+		slog.Info("no enabled accounts found", "component", "models_cache")
+		success, loaded = true, true
 		return
 	}
 
@@ -135,7 +177,8 @@ func RefreshModelsCache(accountDB *db.AccountDB, modelDB *db.ModelDB) {
 	// Collect results from channel
 	for result := range resultChan {
 		if result.err != nil {
-			log.Printf("[ModelsCache] Error fetching models from account %s (ID: %d): %v", result.account.Name, result.account.ID, result.err)
+			slog.Warn("error fetching models from account", "component", "models_cache", "account", result.account.Name, "account_id", result.account.ID, "error", result.err)
+			failedAccounts++
 			continue
 		}
 
@@ -159,7 +202,7 @@ func RefreshModelsCache(accountDB *db.AccountDB, modelDB *db.ModelDB) {
 			}
 		}
 
-		log.Printf("[ModelsCache] Fetched %d models from account %s (ID: %d)", len(result.response.Data), result.account.Name, result.account.ID)
+		slog.Info("fetched models from account", "component", "models_cache", "account", result.account.Name, "account_id", result.account.ID, "models", len(result.response.Data))
 	}
 
 	// Replace the global cache with new data
@@ -172,13 +215,22 @@ func RefreshModelsCache(accountDB *db.AccountDB, modelDB *db.ModelDB) {
 	GlobalModelInfoCache.modelInfos = modelInfoMap
 	GlobalModelInfoCache.mu.Unlock()
 
-	log.Printf("[ModelsCache] Cache refresh completed. Total unique models: %d", len(newModels))
+	success, loaded = true, failedAccounts < len(accounts)
+	slog.Info("models cache refresh completed", "component", "models_cache", "models", len(newModels), "failed_accounts", failedAccounts, "duration", time.Since(start))
 }
 
 // fetchModelsFromAccount fetches models from a specific account's /v1/models endpoint
 func fetchModelsFromAccount(account models.Account) (*ModelsResponse, error) {
-	targetURL := utils.BuildTargetURL(account, "models")
-	log.Printf("[ModelsCache] Fetching models from %s (ID: %d) - URL: %s", account.Name, account.ID, targetURL)
+	// The Anthropic and Gemini models lists are paginated, with small default pages
+	path := "models"
+	switch account.Protocol {
+	case models.ProtocolAnthropic:
+		path = "models?limit=1000"
+	case models.ProtocolGemini:
+		path = "models?pageSize=1000"
+	}
+	targetURL := utils.BuildTargetURL(account, path)
+	slog.Debug("fetching models from account", "component", "models_cache", "account", account.Name, "account_id", account.ID, "url", targetURL)
 
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
@@ -186,7 +238,15 @@ func fetchModelsFromAccount(account models.Account) (*ModelsResponse, error) {
 	}
 
 	req.Header.Set("Accept-Encoding", "identity")
-	req.Header.Set("Authorization", "Bearer "+account.APIKey)
+	switch account.Protocol {
+	case models.ProtocolAnthropic:
+		req.Header.Set("X-Api-Key", account.APIKey)
+		req.Header.Set("anthropic-version", constants.DefaultAnthropicVersion)
+	case models.ProtocolGemini:
+		req.Header.Set("x-goog-api-key", account.APIKey)
+	default:
+		req.Header.Set("Authorization", "Bearer "+account.APIKey)
+	}
 
 	resp, err := utils.HTTPClient.Do(req)
 	if err != nil {
@@ -220,6 +280,10 @@ func fetchModelsFromAccount(account models.Account) (*ModelsResponse, error) {
 		}
 	}
 
+	if account.Protocol == models.ProtocolGemini {
+		return parseGeminiModels(responseBody)
+	}
+
 	var response ModelsResponse
 	if err := json.Unmarshal(responseBody, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
@@ -228,6 +292,35 @@ func fetchModelsFromAccount(account models.Account) (*ModelsResponse, error) {
 	return &response, nil
 }
 
+// geminiModelsResponse represents the Gemini /v1beta/models API response
+type geminiModelsResponse struct {
+	Models []struct {
+		Name                       string   `json:"name"` // models/{model}
+		DisplayName                string   `json:"displayName"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	} `json:"models"`
+}
+
+// parseGeminiModels converts a Gemini models list into the OpenAI models format
+func parseGeminiModels(responseBody []byte) (*ModelsResponse, error) {
+	var gemini geminiModelsResponse
+	if err := json.Unmarshal(responseBody, &gemini); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	response := &ModelsResponse{Object: "list", Success: true}
+	for _, model := range gemini.Models {
+		response.Data = append(response.Data, ModelInfo{
+			ID:                     strings.TrimPrefix(model.Name, "models/"),
+			Object:                 "model",
+			OwnedBy:                "google",
+			DisplayName:            model.DisplayName,
+			SupportedEndpointTypes: []string{"gemini"},
+		})
+	}
+	return response, nil
+}
+
 // ModelsResponse represents the /v1/models API response
 type ModelsResponse struct {
 	Data    []ModelInfo `json:"data"`
@@ -245,12 +338,32 @@ func GetAccountsForModel(modelID string) []models.Account {
 		return []models.Account{}
 	}
 
-	// Return a copy to avoid concurrent access issues
-	result := make([]models.Account, len(accounts))
-	copy(result, accounts)
+	// Return a copy to avoid concurrent access issues, without paused accounts
+	result := make([]models.Account, 0, len(accounts))
+	for _, acc := range accounts {
+		if _, paused := AccountPauseReason(acc.ID); !paused {
+			result = append(result, acc)
+		}
+	}
 	return result
 }
 
+// RemoveAccount drops an account from every cached model until the next refresh
+func RemoveAccount(accountID int) {
+	GlobalModelsCache.mu.Lock()
+	defer GlobalModelsCache.mu.Unlock()
+
+	for modelID, accounts := range GlobalModelsCache.models {
+		kept := make([]models.Account, 0, len(accounts))
+		for _, acc := range accounts {
+			if acc.ID != accountID {
+				kept = append(kept, acc)
+			}
+		}
+		GlobalModelsCache.models[modelID] = kept
+	}
+}
+
 // GetAllModels returns all models (for debug routes)
 func GetAllModels() map[string][]models.Account {
 	GlobalModelsCache.mu.RLock()
@@ -286,6 +399,18 @@ func GetAllAccounts() []models.Account {
 	return result
 }
 
+// GetRoutableAccounts returns all unique accounts from the models cache except paused ones
+func GetRoutableAccounts() []models.Account {
+	accounts := GetAllAccounts()
+	result := make([]models.Account, 0, len(accounts))
+	for _, acc := range accounts {
+		if _, paused := AccountPauseReason(acc.ID); !paused {
+			result = append(result, acc)
+		}
+	}
+	return result
+}
+
 // GetRandomModelIDByPattern returns a random model ID based on pattern matching
 // Supports patterns:
 // - "*" - matches any model
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// accountPause excludes an enabled account from routing, until a time or, when zero, until resumed
+type accountPause struct {
+	reason string
+	until  time.Time
+}
+
+// pausedAccounts holds the paused accounts by ID
+var pausedAccounts = struct {
+	mu     sync.RWMutex
+	pauses map[int]accountPause
+}{
+	pauses: make(map[int]accountPause),
+}
+
+// PauseAccount excludes an account from GetAccountsForModel until the given time, zero meaning until ResumeAccount
+// The account's enabled flag is left untouched
+func PauseAccount(accountID int, reason string, until time.Time) {
+	pausedAccounts.mu.Lock()
+	defer pausedAccounts.mu.Unlock()
+	pausedAccounts.pauses[accountID] = accountPause{reason: reason, until: until}
+}
+
+// ResumeAccount lets a paused account be routed to again
+func ResumeAccount(accountID int) {
+	pausedAccounts.mu.Lock()
+	defer pausedAccounts.mu.Unlock()
+	delete(pausedAccounts.pauses, accountID)
+}
+
+// AccountPauseReason returns why an account is paused, if it is
+func AccountPauseReason(accountID int) (string, bool) {
+	pausedAccounts.mu.RLock()
+	defer pausedAccounts.mu.RUnlock()
+
+	pause, ok := pausedAccounts.pauses[accountID]
+	if !ok || (!pause.until.IsZero() && !time.Now().Before(pause.until)) {
+		return "", false
+	}
+	return pause.reason, true
+}
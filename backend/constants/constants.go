@@ -12,6 +12,36 @@ const (
 	// HTTP Constants
 	StreamBufferSize = 4096
 
+	// MaxUsageCaptureBytes caps how much of a JSON response is buffered to parse token usage
+	MaxUsageCaptureBytes = 4 << 20
+
+	// RequestIDHeader carries the ID correlating a request's logs, accepted from clients and echoed back
+	RequestIDHeader = "X-Request-ID"
+	// UpstreamRequestIDHeader relays the request ID returned by the upstream account
+	UpstreamRequestIDHeader = "X-Upstream-Request-ID"
+
+	// Routing headers set when ROUTING_HEADERS_ENABLED is on
+	AirAliasHeader         = "X-Air-Alias"
+	AirUpstreamModelHeader = "X-Air-Upstream-Model"
+	AirAccountHeader       = "X-Air-Account"
+	AirAttemptsHeader      = "X-Air-Attempts"
+	AirRequestIDHeader     = "X-Air-Request-Id"
+
 	// Cache Constants
 	CounterResetThreshold = (1 << 63) - 100000
+
+	// API Key Constants
+	APIKeyPrefix      = "sk-air-"
+	APIKeyRandomBytes = 24
+
+	// Gin Context Keys
+	ContextKeyAPIKey        = "air_api_key"
+	ContextKeyDeadline      = "air_deadline"
+	ContextKeyHedges        = "air_hedges"
+	ContextKeyUsage         = "air_usage"
+	ContextKeyAccount       = "air_account"        // last account attempted
+	ContextKeyUpstreamModel = "air_upstream_model" // upstream model of the last attempt
+	ContextKeyAlias         = "air_alias"
+	ContextKeyAttempts      = "air_attempts" // upstream attempts for the request log
+	ContextKeyRequestID     = "air_request_id"
 )
@@ -12,6 +12,14 @@ type AccountDB struct {
 	DB *sql.DB
 }
 
+// accountColumns lists the account columns in the order scanned by accountFields
+const accountColumns = `id, name, base_url, api_key, enabled, claude_available, responses_available, protocol, ext, weight, priority, cost_multiplier, budget, budget_period, connect_timeout_ms, first_byte_timeout_ms, idle_timeout_ms, updated_at`
+
+// accountFields returns the scan destinations matching accountColumns
+func accountFields(account *models.Account) []interface{} {
+	return []interface{}{&account.ID, &account.Name, &account.BaseURL, &account.APIKey, &account.Enabled, &account.ClaudeAvailable, &account.ResponsesAvailable, &account.Protocol, &account.Ext, &account.Weight, &account.Priority, &account.CostMultiplier, &account.Budget, &account.BudgetPeriod, &account.ConnectTimeoutMs, &account.FirstByteTimeoutMs, &account.IdleTimeoutMs, &account.UpdatedAt}
+}
+
 // scanAccounts scans account rows from the database
 func scanAccounts(rows *sql.Rows) ([]models.Account, error) {
 	defer rows.Close()
@@ -19,7 +27,7 @@ func scanAccounts(rows *sql.Rows) ([]models.Account, error) {
 	var accounts []models.Account
 	for rows.Next() {
 		var account models.Account
-		err := rows.Scan(&account.ID, &account.Name, &account.BaseURL, &account.APIKey, &account.Enabled, &account.ClaudeAvailable, &account.Ext, &account.UpdatedAt)
+		err := rows.Scan(accountFields(&account)...)
 		if err != nil {
 			return nil, err
 		}
@@ -86,8 +94,8 @@ func (a *AccountDB) CreateAccount(account models.Account) (int64, error) {
 		return 0, fmt.Errorf("account with name '%s' already exists", account.Name)
 	}
 
-	query := `INSERT INTO accounts (name, base_url, api_key, enabled, claude_available, ext, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	result, err := a.DB.Exec(query, account.Name, account.BaseURL, account.APIKey, account.Enabled, account.ClaudeAvailable, account.Ext, common.GetCurrentTimestamp())
+	query := `INSERT INTO accounts (name, base_url, api_key, enabled, claude_available, responses_available, protocol, ext, weight, priority, cost_multiplier, budget, budget_period, connect_timeout_ms, first_byte_timeout_ms, idle_timeout_ms, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := a.DB.Exec(query, account.Name, account.BaseURL, account.APIKey, account.Enabled, account.ClaudeAvailable, account.ResponsesAvailable, account.Protocol, account.Ext, account.Weight, account.Priority, account.CostMultiplier, account.Budget, account.BudgetPeriod, account.ConnectTimeoutMs, account.FirstByteTimeoutMs, account.IdleTimeoutMs, common.GetCurrentTimestamp())
 	if err != nil {
 		return 0, err
 	}
@@ -102,7 +110,7 @@ func (a *AccountDB) CreateAccount(account models.Account) (int64, error) {
 
 // GetAccounts retrieves all accounts from the database
 func (a *AccountDB) GetAccounts() ([]models.Account, error) {
-	query := `SELECT id, name, base_url, api_key, enabled, claude_available, ext, updated_at FROM accounts`
+	query := `SELECT ` + accountColumns + ` FROM accounts`
 	rows, err := a.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -112,7 +120,7 @@ func (a *AccountDB) GetAccounts() ([]models.Account, error) {
 
 // GetEnabledAccounts retrieves all enabled accounts from the database
 func (a *AccountDB) GetEnabledAccounts() ([]models.Account, error) {
-	query := `SELECT id, name, base_url, api_key, enabled, claude_available, ext, updated_at FROM accounts WHERE enabled = 1`
+	query := `SELECT ` + accountColumns + ` FROM accounts WHERE enabled = 1`
 	rows, err := a.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -123,8 +131,8 @@ func (a *AccountDB) GetEnabledAccounts() ([]models.Account, error) {
 // GetAccount retrieves a specific account by ID
 func (a *AccountDB) GetAccount(id int) (models.Account, error) {
 	var account models.Account
-	query := `SELECT id, name, base_url, api_key, enabled, claude_available, ext, updated_at FROM accounts WHERE id = ?`
-	err := a.DB.QueryRow(query, id).Scan(&account.ID, &account.Name, &account.BaseURL, &account.APIKey, &account.Enabled, &account.ClaudeAvailable, &account.Ext, &account.UpdatedAt)
+	query := `SELECT ` + accountColumns + ` FROM accounts WHERE id = ?`
+	err := a.DB.QueryRow(query, id).Scan(accountFields(&account)...)
 	if err != nil {
 		return account, err
 	}
@@ -143,8 +151,8 @@ func (a *AccountDB) UpdateAccount(account models.Account) error {
 		return fmt.Errorf("account with name '%s' already exists", account.Name)
 	}
 
-	query := `UPDATE accounts SET name = ?, base_url = ?, api_key = ?, enabled = ?, claude_available = ?, ext = ?, updated_at = ? WHERE id = ?`
-	_, err = a.DB.Exec(query, account.Name, account.BaseURL, account.APIKey, account.Enabled, account.ClaudeAvailable, account.Ext, common.GetCurrentTimestamp(), account.ID)
+	query := `UPDATE accounts SET name = ?, base_url = ?, api_key = ?, enabled = ?, claude_available = ?, responses_available = ?, protocol = ?, ext = ?, weight = ?, priority = ?, cost_multiplier = ?, budget = ?, budget_period = ?, connect_timeout_ms = ?, first_byte_timeout_ms = ?, idle_timeout_ms = ?, updated_at = ? WHERE id = ?`
+	_, err = a.DB.Exec(query, account.Name, account.BaseURL, account.APIKey, account.Enabled, account.ClaudeAvailable, account.ResponsesAvailable, account.Protocol, account.Ext, account.Weight, account.Priority, account.CostMultiplier, account.Budget, account.BudgetPeriod, account.ConnectTimeoutMs, account.FirstByteTimeoutMs, account.IdleTimeoutMs, common.GetCurrentTimestamp(), account.ID)
 	return err
 }
 
@@ -166,7 +174,7 @@ func (a *AccountDB) GetPaginatedAccounts(page, pageSize int, search string) ([]m
 	}
 
 	// Get paginated accounts
-	baseQuery := `SELECT id, name, base_url, api_key, enabled, claude_available, ext, updated_at FROM accounts`
+	baseQuery := `SELECT ` + accountColumns + ` FROM accounts`
 	query, args := buildPaginatedQuery(baseQuery, search, page, pageSize)
 
 	rows, err := a.DB.Query(query, args...)
@@ -185,7 +193,12 @@ func (a *AccountDB) GetPaginatedAccounts(page, pageSize int, search string) ([]m
 // DeleteAccount deletes an account by ID
 func (a *AccountDB) DeleteAccount(id int) error {
 	query := `DELETE FROM accounts WHERE id = ?`
-	_, err := a.DB.Exec(query, id)
+	if _, err := a.DB.Exec(query, id); err != nil {
+		return err
+	}
+
+	// Objects stored on the account are unreachable once it is gone
+	_, err := a.DB.Exec(`DELETE FROM resource_affinity WHERE account_id = ?`, id)
 	return err
 }
 
@@ -205,3 +218,10 @@ func (a *AccountDB) ToggleAccount(id int) error {
 	_, err = a.DB.Exec(updateQuery, newEnabled, common.GetCurrentTimestamp(), id)
 	return err
 }
+
+// SetAccountEnabled sets the enabled status of an account
+func (a *AccountDB) SetAccountEnabled(id int, enabled bool) error {
+	query := `UPDATE accounts SET enabled = ?, updated_at = ? WHERE id = ?`
+	_, err := a.DB.Exec(query, enabled, common.GetCurrentTimestamp(), id)
+	return err
+}
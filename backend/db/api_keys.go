@@ -0,0 +1,240 @@
+package db
+
+import (
+	"air_router/models"
+	"air_router/utils/common"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// APIKeyDB represents the database operations for client API keys
+type APIKeyDB struct {
+	DB *sql.DB
+}
+
+// scanAPIKeys scans api key rows from the database
+func scanAPIKeys(rows *sql.Rows) ([]models.APIKey, error) {
+	defer rows.Close()
+
+	var apiKeys []models.APIKey
+	for rows.Next() {
+		var apiKey models.APIKey
+		err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Key, &apiKey.Enabled, &apiKey.Ext, &apiKey.RPMLimit, &apiKey.TokensPerDayLimit, &apiKey.MonthlyTokenLimit, &apiKey.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// APIKeyNameExists checks if an api key with the given name already exists, excluding the specified ID
+func (k *APIKeyDB) APIKeyNameExists(name string, excludeID int) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM api_keys WHERE name = ? AND id != ?`
+	err := k.DB.QueryRow(query, name, excludeID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateAPIKey inserts a new api key into the database
+func (k *APIKeyDB) CreateAPIKey(apiKey models.APIKey) (int64, error) {
+	// Check if an api key with the same name already exists
+	exists, err := k.APIKeyNameExists(apiKey.Name, 0)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, fmt.Errorf("api key with name '%s' already exists", apiKey.Name)
+	}
+
+	query := `INSERT INTO api_keys (name, key, enabled, ext, rpm_limit, tokens_per_day_limit, monthly_token_limit, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := k.DB.Exec(query, apiKey.Name, apiKey.Key, apiKey.Enabled, apiKey.Ext, apiKey.RPMLimit, apiKey.TokensPerDayLimit, apiKey.MonthlyTokenLimit, common.GetCurrentTimestamp())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetAPIKeys retrieves all api keys from the database
+func (k *APIKeyDB) GetAPIKeys() ([]models.APIKey, error) {
+	query := `SELECT id, name, key, enabled, ext, rpm_limit, tokens_per_day_limit, monthly_token_limit, updated_at FROM api_keys ORDER BY id DESC`
+	rows, err := k.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	return scanAPIKeys(rows)
+}
+
+// GetAPIKey retrieves a specific api key by ID
+func (k *APIKeyDB) GetAPIKey(id int) (models.APIKey, error) {
+	var apiKey models.APIKey
+	query := `SELECT id, name, key, enabled, ext, rpm_limit, tokens_per_day_limit, monthly_token_limit, updated_at FROM api_keys WHERE id = ?`
+	err := k.DB.QueryRow(query, id).Scan(&apiKey.ID, &apiKey.Name, &apiKey.Key, &apiKey.Enabled, &apiKey.Ext, &apiKey.RPMLimit, &apiKey.TokensPerDayLimit, &apiKey.MonthlyTokenLimit, &apiKey.UpdatedAt)
+	return apiKey, err
+}
+
+// GetAPIKeyByKey retrieves a specific api key by its secret value
+func (k *APIKeyDB) GetAPIKeyByKey(key string) (models.APIKey, error) {
+	var apiKey models.APIKey
+	query := `SELECT id, name, key, enabled, ext, rpm_limit, tokens_per_day_limit, monthly_token_limit, updated_at FROM api_keys WHERE key = ?`
+	err := k.DB.QueryRow(query, key).Scan(&apiKey.ID, &apiKey.Name, &apiKey.Key, &apiKey.Enabled, &apiKey.Ext, &apiKey.RPMLimit, &apiKey.TokensPerDayLimit, &apiKey.MonthlyTokenLimit, &apiKey.UpdatedAt)
+	return apiKey, err
+}
+
+// UpdateAPIKey updates an existing api key
+func (k *APIKeyDB) UpdateAPIKey(apiKey models.APIKey) error {
+	// Check if another api key with the same name already exists
+	exists, err := k.APIKeyNameExists(apiKey.Name, apiKey.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("api key with name '%s' already exists", apiKey.Name)
+	}
+
+	query := `UPDATE api_keys SET name = ?, key = ?, enabled = ?, ext = ?, rpm_limit = ?, tokens_per_day_limit = ?, monthly_token_limit = ?, updated_at = ? WHERE id = ?`
+	_, err = k.DB.Exec(query, apiKey.Name, apiKey.Key, apiKey.Enabled, apiKey.Ext, apiKey.RPMLimit, apiKey.TokensPerDayLimit, apiKey.MonthlyTokenLimit, common.GetCurrentTimestamp(), apiKey.ID)
+	return err
+}
+
+// GetPaginatedAPIKeys retrieves api keys with pagination and optional search by name
+func (k *APIKeyDB) GetPaginatedAPIKeys(page, pageSize int, search string) ([]models.APIKey, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM api_keys`
+	countArgs := []interface{}{}
+
+	if search != "" {
+		countQuery += ` WHERE name LIKE ?`
+		countArgs = append(countArgs, "%"+search+"%")
+	}
+
+	err := k.DB.QueryRow(countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated api keys
+	baseQuery := `SELECT id, name, key, enabled, ext, rpm_limit, tokens_per_day_limit, monthly_token_limit, updated_at FROM api_keys`
+	query, args := buildPaginatedQuery(baseQuery, search, page, pageSize)
+
+	rows, err := k.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	apiKeys, err := scanAPIKeys(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return apiKeys, total, nil
+}
+
+// DeleteAPIKey deletes an api key and its usage counters by ID
+func (k *APIKeyDB) DeleteAPIKey(id int) error {
+	query := `DELETE FROM api_keys WHERE id = ?`
+	if _, err := k.DB.Exec(query, id); err != nil {
+		return err
+	}
+
+	_, err := k.DB.Exec(`DELETE FROM api_key_usage WHERE api_key_id = ?`, id)
+	return err
+}
+
+// ToggleAPIKey toggles the enabled status of an api key
+func (k *APIKeyDB) ToggleAPIKey(id int) error {
+	// First get the current status
+	var enabled bool
+	query := `SELECT enabled FROM api_keys WHERE id = ?`
+	err := k.DB.QueryRow(query, id).Scan(&enabled)
+	if err != nil {
+		return err
+	}
+
+	// Toggle the status and update updated_at
+	updateQuery := `UPDATE api_keys SET enabled = ?, updated_at = ? WHERE id = ?`
+	_, err = k.DB.Exec(updateQuery, !enabled, common.GetCurrentTimestamp(), id)
+	return err
+}
+
+// AddAPIKeyUsage adds the counts of delta to each given usage period (period -> period_key) of an api key
+func (k *APIKeyDB) AddAPIKeyUsage(apiKeyID int, periods map[string]string, delta models.UsageCounter) error {
+	if len(periods) == 0 {
+		return nil
+	}
+
+	now := common.GetCurrentTimestamp()
+	values := make([]string, 0, len(periods))
+	args := make([]interface{}, 0, len(periods)*7)
+	for period, periodKey := range periods {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, apiKeyID, period, periodKey, delta.Requests, delta.Tokens, delta.HedgedRequests, now)
+	}
+
+	query := `INSERT INTO api_key_usage (api_key_id, period, period_key, requests, tokens, hedged_requests, updated_at) VALUES ` + strings.Join(values, ", ") + `
+	ON CONFLICT (api_key_id, period, period_key) DO UPDATE SET
+		requests = requests + excluded.requests,
+		tokens = tokens + excluded.tokens,
+		hedged_requests = hedged_requests + excluded.hedged_requests,
+		updated_at = excluded.updated_at`
+	_, err := k.DB.Exec(query, args...)
+	return err
+}
+
+// GetAPIKeyUsage returns the counters of an api key for each given usage period (period -> period_key)
+// Periods without a stored row are returned as zero counters
+func (k *APIKeyDB) GetAPIKeyUsage(apiKeyID int, periods map[string]string) (map[string]models.UsageCounter, error) {
+	result := make(map[string]models.UsageCounter, len(periods))
+	if len(periods) == 0 {
+		return result, nil
+	}
+
+	conditions := make([]string, 0, len(periods))
+	args := []interface{}{apiKeyID}
+	for period, periodKey := range periods {
+		conditions = append(conditions, "(period = ? AND period_key = ?)")
+		args = append(args, period, periodKey)
+		result[period] = models.UsageCounter{}
+	}
+
+	query := `SELECT period, requests, tokens, hedged_requests FROM api_key_usage WHERE api_key_id = ? AND (` + strings.Join(conditions, " OR ") + `)`
+	rows, err := k.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var period string
+		var counter models.UsageCounter
+		if err := rows.Scan(&period, &counter.Requests, &counter.Tokens, &counter.HedgedRequests); err != nil {
+			return nil, err
+		}
+		result[period] = counter
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteAPIKeyUsageBefore deletes counters of a usage period older than the given period_key
+func (k *APIKeyDB) DeleteAPIKeyUsageBefore(period, periodKey string) error {
+	query := `DELETE FROM api_key_usage WHERE period = ? AND period_key < ?`
+	_, err := k.DB.Exec(query, period, periodKey)
+	return err
+}
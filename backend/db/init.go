@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -40,7 +41,17 @@ func createTables(conn *sql.DB) error {
 		api_key TEXT NOT NULL,
 		enabled BOOLEAN NOT NULL DEFAULT true,
 		claude_available INTEGER NOT NULL DEFAULT 0,
+		responses_available INTEGER NOT NULL DEFAULT 0,
+		protocol TEXT NOT NULL DEFAULT 'openai',
 		ext TEXT,
+		weight INTEGER NOT NULL DEFAULT 1,
+		priority INTEGER NOT NULL DEFAULT 0,
+		cost_multiplier REAL NOT NULL DEFAULT 1, -- applied to the price catalog
+		budget REAL NOT NULL DEFAULT 0, -- USD, 0 means unlimited
+		budget_period TEXT NOT NULL DEFAULT 'monthly', -- monthly, total
+		connect_timeout_ms INTEGER NOT NULL DEFAULT 0, -- 0 uses the global default
+		first_byte_timeout_ms INTEGER NOT NULL DEFAULT 0,
+		idle_timeout_ms INTEGER NOT NULL DEFAULT 0,
 		updated_at INTEGER NOT NULL DEFAULT 0
 	);`
 
@@ -55,6 +66,9 @@ func createTables(conn *sql.DB) error {
 		model_id TEXT NOT NULL UNIQUE,
 		ass_model_ids TEXT, -- JSON array of associated model IDs
 		provider TEXT NOT NULL, -- chat, claude, codex, gemini
+		strategy TEXT NOT NULL DEFAULT 'weighted', -- random, round_robin, weighted, failover, least_latency, least_inflight
+		hedge_delay_ms INTEGER NOT NULL DEFAULT 0, -- 0 disables hedged requests
+		rewrite_model BOOLEAN NOT NULL DEFAULT false, -- report the alias as the model of responses
 		enabled BOOLEAN NOT NULL DEFAULT true,
 		updated_at INTEGER NOT NULL DEFAULT 0
 	);`
@@ -63,5 +77,215 @@ func createTables(conn *sql.DB) error {
 		return err
 	}
 
+	// Create api_keys table
+	createAPIKeysTableQuery := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		key TEXT NOT NULL UNIQUE,
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		ext TEXT,
+		rpm_limit INTEGER NOT NULL DEFAULT 0, -- 0 means unlimited
+		tokens_per_day_limit INTEGER NOT NULL DEFAULT 0,
+		monthly_token_limit INTEGER NOT NULL DEFAULT 0,
+		updated_at INTEGER NOT NULL DEFAULT 0
+	);`
+
+	if _, err := conn.Exec(createAPIKeysTableQuery); err != nil {
+		return err
+	}
+
+	// Create api_key_usage table holding per-period request and token counters
+	createAPIKeyUsageTableQuery := `
+	CREATE TABLE IF NOT EXISTS api_key_usage (
+		api_key_id INTEGER NOT NULL,
+		period TEXT NOT NULL, -- minute, day, month
+		period_key TEXT NOT NULL, -- e.g. 2006-01-02T15:04, 2006-01-02, 2006-01 (UTC)
+		requests INTEGER NOT NULL DEFAULT 0,
+		tokens INTEGER NOT NULL DEFAULT 0,
+		hedged_requests INTEGER NOT NULL DEFAULT 0, -- duplicate upstream requests sent by hedging
+		updated_at INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (api_key_id, period, period_key)
+	);`
+
+	if _, err := conn.Exec(createAPIKeyUsageTableQuery); err != nil {
+		return err
+	}
+
+	// Create resource_affinity table mapping stored upstream objects to the account that created them
+	createResourceAffinityTableQuery := `
+	CREATE TABLE IF NOT EXISTS resource_affinity (
+		resource_id TEXT PRIMARY KEY, -- e.g. file-abc, batch_abc, msgbatch_abc, resp_abc
+		account_id INTEGER NOT NULL,
+		kind TEXT NOT NULL, -- file, upload, batch, response, vector_store, fine_tuning_job
+		created_at INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_resource_affinity_created_at ON resource_affinity (created_at);`
+
+	if _, err := conn.Exec(createResourceAffinityTableQuery); err != nil {
+		return err
+	}
+
+	// Create usage_records table holding one accounting row per proxied request
+	createUsageRecordsTableQuery := `
+	CREATE TABLE IF NOT EXISTS usage_records (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at INTEGER NOT NULL DEFAULT 0,
+		api_key_id INTEGER NOT NULL DEFAULT 0, -- 0 when client key auth is disabled
+		account_id INTEGER NOT NULL DEFAULT 0, -- last account attempted, 0 when none was
+		alias TEXT NOT NULL DEFAULT '', -- empty when routed by upstream model ID
+		upstream_model TEXT NOT NULL DEFAULT '',
+		path TEXT NOT NULL DEFAULT '',
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		cached_tokens INTEGER NOT NULL DEFAULT 0, -- part of prompt_tokens read from the prompt cache
+		cost REAL NOT NULL DEFAULT 0, -- USD, from the price catalog and the account multiplier
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		status INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_usage_records_created_at ON usage_records (created_at);
+	CREATE INDEX IF NOT EXISTS idx_usage_records_account_id ON usage_records (account_id, created_at);`
+
+	if _, err := conn.Exec(createUsageRecordsTableQuery); err != nil {
+		return err
+	}
+
+	// Create model_prices table holding the price catalog of upstream models
+	createModelPricesTableQuery := `
+	CREATE TABLE IF NOT EXISTS model_prices (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		model_id TEXT NOT NULL UNIQUE, -- upstream model ID, a trailing * matches a prefix
+		input_price REAL NOT NULL DEFAULT 0, -- USD per million tokens
+		output_price REAL NOT NULL DEFAULT 0,
+		cached_input_price REAL NOT NULL DEFAULT 0, -- 0 bills cached tokens at input_price
+		updated_at INTEGER NOT NULL DEFAULT 0
+	);`
+
+	if _, err := conn.Exec(createModelPricesTableQuery); err != nil {
+		return err
+	}
+
+	// Create request_logs table holding the audit log of proxied requests when request logging is enabled
+	createRequestLogsTableQuery := `
+	CREATE TABLE IF NOT EXISTS request_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at INTEGER NOT NULL DEFAULT 0,
+		request_id TEXT NOT NULL DEFAULT '', -- X-Request-ID echoed to the client and attached to its log lines
+		method TEXT NOT NULL DEFAULT '',
+		path TEXT NOT NULL DEFAULT '',
+		query TEXT NOT NULL DEFAULT '', -- key-like parameters redacted
+		client_ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		api_key_id INTEGER NOT NULL DEFAULT 0,
+		alias TEXT NOT NULL DEFAULT '',
+		upstream_model TEXT NOT NULL DEFAULT '',
+		account_id INTEGER NOT NULL DEFAULT 0, -- account that produced the response, 0 when none did
+		account_name TEXT NOT NULL DEFAULT '', -- kept so logs outlive deleted accounts
+		attempts TEXT NOT NULL DEFAULT '[]', -- JSON list of upstream attempts
+		status INTEGER NOT NULL DEFAULT 0,
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		request_body TEXT NOT NULL DEFAULT '', -- only with AUDIT_LOG_BODIES, capped and redacted
+		response_body TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_request_logs_created_at ON request_logs (created_at);`
+
+	if _, err := conn.Exec(createRequestLogsTableQuery); err != nil {
+		return err
+	}
+
+	// Add columns introduced after the tables were first created
+	if err := migrateTables(conn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// columnMigration describes a column that must exist on a table
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations lists columns added to existing tables over time
+var columnMigrations = []columnMigration{
+	{"accounts", "weight", "INTEGER NOT NULL DEFAULT 1"},
+	{"accounts", "priority", "INTEGER NOT NULL DEFAULT 0"},
+	{"accounts", "connect_timeout_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"accounts", "first_byte_timeout_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"accounts", "idle_timeout_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"accounts", "protocol", "TEXT NOT NULL DEFAULT 'openai'"},
+	{"accounts", "responses_available", "INTEGER NOT NULL DEFAULT 0"},
+	{"accounts", "cost_multiplier", "REAL NOT NULL DEFAULT 1"},
+	{"accounts", "budget", "REAL NOT NULL DEFAULT 0"},
+	{"accounts", "budget_period", "TEXT NOT NULL DEFAULT 'monthly'"},
+	{"models", "strategy", "TEXT NOT NULL DEFAULT 'weighted'"},
+	{"models", "hedge_delay_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"models", "rewrite_model", "BOOLEAN NOT NULL DEFAULT false"},
+	{"api_keys", "rpm_limit", "INTEGER NOT NULL DEFAULT 0"},
+	{"api_keys", "tokens_per_day_limit", "INTEGER NOT NULL DEFAULT 0"},
+	{"api_keys", "monthly_token_limit", "INTEGER NOT NULL DEFAULT 0"},
+	{"api_key_usage", "hedged_requests", "INTEGER NOT NULL DEFAULT 0"},
+	{"usage_records", "cost", "REAL NOT NULL DEFAULT 0"},
+	{"request_logs", "request_id", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// columnBackfills sets the value of existing rows when a column is added, keyed by table.column
+var columnBackfills = map[string]string{
+	// Accounts created before translation existed were sent /v1/responses as-is, keep it that way
+	"accounts.responses_available": `UPDATE accounts SET responses_available = 1 WHERE protocol = 'openai'`,
+}
+
+// migrateTables adds missing columns to tables created by older versions
+func migrateTables(conn *sql.DB) error {
+	for _, m := range columnMigrations {
+		exists, err := columnExists(conn, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.table, m.column, m.definition)
+		if _, err := conn.Exec(query); err != nil {
+			return err
+		}
+		if backfill, exists := columnBackfills[m.table+"."+m.column]; exists {
+			if _, err := conn.Exec(backfill); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+// columnExists checks whether a column exists on a table
+func columnExists(conn *sql.DB, table, column string) (bool, error) {
+	rows, err := conn.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -13,32 +13,50 @@ type ModelDB struct {
 	DB *sql.DB
 }
 
+// modelColumns lists the model columns in the order read by scanModel
+const modelColumns = `id, model_id, ass_model_ids, provider, strategy, hedge_delay_ms, rewrite_model, enabled, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanModel scans one model row selected with modelColumns
+func scanModel(row rowScanner) (models.Model, error) {
+	var model models.Model
+	var assModelIDsJSON sql.NullString
+	var provider, strategy string
+
+	err := row.Scan(&model.ID, &model.ModelID, &assModelIDsJSON, &provider, &strategy, &model.HedgeDelayMs, &model.RewriteModel, &model.Enabled, &model.UpdatedAt)
+	if err != nil {
+		return model, err
+	}
+
+	// Parse associated model IDs from JSON
+	if assModelIDsJSON.Valid {
+		var assModelIDs []string
+		if err := json.Unmarshal([]byte(assModelIDsJSON.String), &assModelIDs); err == nil {
+			model.AssModelIDs = assModelIDs
+		}
+	}
+
+	// Parse provider and routing strategy
+	model.Provider = models.Provider(provider)
+	model.Strategy = models.RoutingStrategy(strategy)
+
+	return model, nil
+}
+
 // scanModels scans model rows from the database
 func scanModels(rows *sql.Rows) ([]models.Model, error) {
 	defer rows.Close()
 
 	var modelsList []models.Model
 	for rows.Next() {
-		var model models.Model
-		var assModelIDsJSON sql.NullString
-		var provider string
-
-		err := rows.Scan(&model.ID, &model.ModelID, &assModelIDsJSON, &provider, &model.Enabled, &model.UpdatedAt)
+		model, err := scanModel(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		// Parse associated model IDs from JSON
-		if assModelIDsJSON.Valid {
-			var assModelIDs []string
-			if err := json.Unmarshal([]byte(assModelIDsJSON.String), &assModelIDs); err == nil {
-				model.AssModelIDs = assModelIDs
-			}
-		}
-
-		// Parse provider
-		model.Provider = models.Provider(provider)
-
 		modelsList = append(modelsList, model)
 	}
 
@@ -92,8 +110,8 @@ func (m *ModelDB) CreateModel(model models.Model) (int64, error) {
 		assModelIDsJSON = sql.NullString{String: string(jsonData), Valid: true}
 	}
 
-	query := `INSERT INTO models (model_id, ass_model_ids, provider, enabled, updated_at) VALUES (?, ?, ?, ?, ?)`
-	result, err := m.DB.Exec(query, model.ModelID, assModelIDsJSON, string(model.Provider), model.Enabled, common.GetCurrentTimestamp())
+	query := `INSERT INTO models (model_id, ass_model_ids, provider, strategy, hedge_delay_ms, rewrite_model, enabled, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := m.DB.Exec(query, model.ModelID, assModelIDsJSON, string(model.Provider), string(model.Strategy), model.HedgeDelayMs, model.RewriteModel, model.Enabled, common.GetCurrentTimestamp())
 	if err != nil {
 		return 0, err
 	}
@@ -108,7 +126,7 @@ func (m *ModelDB) CreateModel(model models.Model) (int64, error) {
 
 // GetModels retrieves all models from the database
 func (m *ModelDB) GetModels() ([]models.Model, error) {
-	query := `SELECT id, model_id, ass_model_ids, provider, enabled, updated_at FROM models`
+	query := `SELECT ` + modelColumns + ` FROM models`
 	rows, err := m.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -118,7 +136,7 @@ func (m *ModelDB) GetModels() ([]models.Model, error) {
 
 // GetEnabledModels retrieves all enabled models from the database
 func (m *ModelDB) GetEnabledModels() ([]models.Model, error) {
-	query := `SELECT id, model_id, ass_model_ids, provider, enabled, updated_at FROM models WHERE enabled = 1`
+	query := `SELECT ` + modelColumns + ` FROM models WHERE enabled = 1`
 	rows, err := m.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -128,7 +146,7 @@ func (m *ModelDB) GetEnabledModels() ([]models.Model, error) {
 
 // GetEnabledModelsByProvider retrieves all enabled models for a specific provider from the database
 func (m *ModelDB) GetEnabledModelsByProvider(provider models.Provider) ([]models.Model, error) {
-	query := `SELECT id, model_id, ass_model_ids, provider, enabled, updated_at FROM models WHERE enabled = 1 AND provider = ?`
+	query := `SELECT ` + modelColumns + ` FROM models WHERE enabled = 1 AND provider = ?`
 	rows, err := m.DB.Query(query, provider)
 	if err != nil {
 		return nil, err
@@ -148,28 +166,8 @@ func (m *ModelDB) GetModelByModelID(modelID string) (models.Model, error) {
 
 // getModelByField retrieves a specific model by field name and value
 func (m *ModelDB) getModelByField(field string, value interface{}) (models.Model, error) {
-	var model models.Model
-	var assModelIDsJSON sql.NullString
-	var provider string
-
-	query := fmt.Sprintf(`SELECT id, model_id, ass_model_ids, provider, enabled, updated_at FROM models WHERE %s = ?`, field)
-	err := m.DB.QueryRow(query, value).Scan(&model.ID, &model.ModelID, &assModelIDsJSON, &provider, &model.Enabled, &model.UpdatedAt)
-	if err != nil {
-		return model, err
-	}
-
-	// Parse associated model IDs from JSON
-	if assModelIDsJSON.Valid {
-		var assModelIDs []string
-		if err := json.Unmarshal([]byte(assModelIDsJSON.String), &assModelIDs); err == nil {
-			model.AssModelIDs = assModelIDs
-		}
-	}
-
-	// Parse provider
-	model.Provider = models.Provider(provider)
-
-	return model, nil
+	query := fmt.Sprintf(`SELECT `+modelColumns+` FROM models WHERE %s = ?`, field)
+	return scanModel(m.DB.QueryRow(query, value))
 }
 
 // UpdateModel updates an existing model
@@ -193,8 +191,8 @@ func (m *ModelDB) UpdateModel(model models.Model) error {
 		assModelIDsJSON = sql.NullString{String: string(jsonData), Valid: true}
 	}
 
-	query := `UPDATE models SET model_id = ?, ass_model_ids = ?, provider = ?, enabled = ?, updated_at = ? WHERE id = ?`
-	_, err = m.DB.Exec(query, model.ModelID, assModelIDsJSON, string(model.Provider), model.Enabled, common.GetCurrentTimestamp(), model.ID)
+	query := `UPDATE models SET model_id = ?, ass_model_ids = ?, provider = ?, strategy = ?, hedge_delay_ms = ?, rewrite_model = ?, enabled = ?, updated_at = ? WHERE id = ?`
+	_, err = m.DB.Exec(query, model.ModelID, assModelIDsJSON, string(model.Provider), string(model.Strategy), model.HedgeDelayMs, model.RewriteModel, model.Enabled, common.GetCurrentTimestamp(), model.ID)
 	return err
 }
 
@@ -224,7 +222,7 @@ func (m *ModelDB) ToggleModel(id int) error {
 
 // SearchModels searches for models by model_id or provider
 func (m *ModelDB) SearchModels(search string) ([]models.Model, error) {
-	query := `SELECT id, model_id, ass_model_ids, provider, enabled, updated_at FROM models WHERE model_id LIKE ? OR provider LIKE ?`
+	query := `SELECT ` + modelColumns + ` FROM models WHERE model_id LIKE ? OR provider LIKE ?`
 	searchPattern := "%" + search + "%"
 	rows, err := m.DB.Query(query, searchPattern, searchPattern)
 	if err != nil {
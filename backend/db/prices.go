@@ -0,0 +1,119 @@
+package db
+
+import (
+	"air_router/models"
+	"air_router/utils/common"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// PriceDB represents the database operations for the model price catalog
+type PriceDB struct {
+	DB *sql.DB
+}
+
+// priceColumns lists the model_prices columns in the order scanned by priceFields
+const priceColumns = `id, model_id, input_price, output_price, cached_input_price, updated_at`
+
+// priceFields returns the scan destinations matching priceColumns
+func priceFields(price *models.ModelPrice) []interface{} {
+	return []interface{}{&price.ID, &price.ModelID, &price.InputPrice, &price.OutputPrice, &price.CachedInputPrice, &price.UpdatedAt}
+}
+
+// GetPrices retrieves the whole price catalog ordered by model ID
+func (p *PriceDB) GetPrices() ([]models.ModelPrice, error) {
+	rows, err := p.DB.Query(`SELECT ` + priceColumns + ` FROM model_prices ORDER BY model_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []models.ModelPrice
+	for rows.Next() {
+		var price models.ModelPrice
+		if err := rows.Scan(priceFields(&price)...); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+// GetPrice retrieves a price by ID
+func (p *PriceDB) GetPrice(id int) (models.ModelPrice, error) {
+	var price models.ModelPrice
+	err := p.DB.QueryRow(`SELECT `+priceColumns+` FROM model_prices WHERE id = ?`, id).Scan(priceFields(&price)...)
+	return price, err
+}
+
+// GetPriceForModel returns the price of an upstream model, an exact entry winning over the longest matching prefix
+func (p *PriceDB) GetPriceForModel(modelID string) (models.ModelPrice, bool, error) {
+	var price models.ModelPrice
+	err := p.DB.QueryRow(`SELECT `+priceColumns+` FROM model_prices WHERE model_id = ?`, modelID).Scan(priceFields(&price)...)
+	if err == nil {
+		return price, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return price, false, err
+	}
+
+	prices, err := p.GetPrices()
+	if err != nil {
+		return price, false, err
+	}
+	found := false
+	for _, candidate := range prices {
+		prefix, isPattern := strings.CutSuffix(candidate.ModelID, "*")
+		if isPattern && strings.HasPrefix(modelID, prefix) && (!found || len(candidate.ModelID) > len(price.ModelID)) {
+			price, found = candidate, true
+		}
+	}
+	return price, found, nil
+}
+
+// priceModelExists checks if a price for the model ID already exists, excluding the specified ID
+func (p *PriceDB) priceModelExists(modelID string, excludeID int) (bool, error) {
+	var count int
+	err := p.DB.QueryRow(`SELECT COUNT(*) FROM model_prices WHERE model_id = ? AND id != ?`, modelID, excludeID).Scan(&count)
+	return count > 0, err
+}
+
+// CreatePrice inserts a new price into the catalog
+func (p *PriceDB) CreatePrice(price models.ModelPrice) (int64, error) {
+	exists, err := p.priceModelExists(price.ModelID, 0)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, fmt.Errorf("price for model '%s' already exists", price.ModelID)
+	}
+
+	query := `INSERT INTO model_prices (model_id, input_price, output_price, cached_input_price, updated_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := p.DB.Exec(query, price.ModelID, price.InputPrice, price.OutputPrice, price.CachedInputPrice, common.GetCurrentTimestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdatePrice updates an existing price
+func (p *PriceDB) UpdatePrice(price models.ModelPrice) error {
+	exists, err := p.priceModelExists(price.ModelID, price.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("price for model '%s' already exists", price.ModelID)
+	}
+
+	query := `UPDATE model_prices SET model_id = ?, input_price = ?, output_price = ?, cached_input_price = ?, updated_at = ? WHERE id = ?`
+	_, err = p.DB.Exec(query, price.ModelID, price.InputPrice, price.OutputPrice, price.CachedInputPrice, common.GetCurrentTimestamp(), price.ID)
+	return err
+}
+
+// DeletePrice deletes a price by ID
+func (p *PriceDB) DeletePrice(id int) error {
+	_, err := p.DB.Exec(`DELETE FROM model_prices WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"air_router/models"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// RequestLogDB represents the database operations for request audit logs
+type RequestLogDB struct {
+	DB *sql.DB
+}
+
+// requestLogColumns lists the columns of request_logs in scan order, bodies excluded
+const requestLogColumns = `id, created_at, request_id, method, path, query, client_ip, user_agent, api_key_id, alias, upstream_model, account_id, account_name, attempts, status, latency_ms, prompt_tokens, completion_tokens`
+
+// CreateRequestLog inserts the audit log of a proxied request
+func (r *RequestLogDB) CreateRequestLog(entry models.RequestLog) error {
+	attempts, err := json.Marshal(entry.Attempts)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO request_logs (created_at, request_id, method, path, query, client_ip, user_agent, api_key_id, alias, upstream_model, account_id, account_name, attempts, status, latency_ms, prompt_tokens, completion_tokens, request_body, response_body) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.DB.Exec(query, entry.CreatedAt, entry.RequestID, entry.Method, entry.Path, entry.Query, entry.ClientIP, entry.UserAgent, entry.APIKeyID, entry.Alias, entry.UpstreamModel, entry.AccountID, entry.AccountName, string(attempts), entry.Status, entry.LatencyMs, entry.PromptTokens, entry.CompletionTokens, entry.RequestBody, entry.ResponseBody)
+	return err
+}
+
+// GetPaginatedRequestLogs returns the matching request logs newest first, without their bodies
+// The search term matches the path, alias, upstream model and account name
+func (r *RequestLogDB) GetPaginatedRequestLogs(page, pageSize int, search string, filter models.RequestLogFilter) ([]models.RequestLog, int, error) {
+	var conditions []string
+	var args []interface{}
+	if search != "" {
+		searchPattern := "%" + search + "%"
+		conditions = append(conditions, `(path LIKE ? OR alias LIKE ? OR upstream_model LIKE ? OR account_name LIKE ?)`)
+		args = append(args, searchPattern, searchPattern, searchPattern, searchPattern)
+	}
+	if filter.RequestID != "" {
+		conditions = append(conditions, `request_id = ?`)
+		args = append(args, filter.RequestID)
+	}
+	if filter.Status != 0 {
+		conditions = append(conditions, `status = ?`)
+		args = append(args, filter.Status)
+	}
+	if filter.AccountID != 0 {
+		conditions = append(conditions, `account_id = ?`)
+		args = append(args, filter.AccountID)
+	}
+	if filter.APIKeyID != 0 {
+		conditions = append(conditions, `api_key_id = ?`)
+		args = append(args, filter.APIKeyID)
+	}
+	if filter.Alias != "" {
+		conditions = append(conditions, `alias = ?`)
+		args = append(args, filter.Alias)
+	}
+	if filter.From != 0 {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, filter.From)
+	}
+	if filter.To != 0 {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	// Get total count
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM request_logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated logs
+	query := `SELECT ` + requestLogColumns + ` FROM request_logs` + where + ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	rows, err := r.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.RequestLog{}
+	for rows.Next() {
+		entry, err := scanRequestLog(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// GetRequestLog returns a request log with its bodies
+func (r *RequestLogDB) GetRequestLog(id int64) (models.RequestLog, error) {
+	query := `SELECT ` + requestLogColumns + `, request_body, response_body FROM request_logs WHERE id = ?`
+	var requestBody, responseBody string
+	entry, err := scanRequestLog(r.DB.QueryRow(query, id), &requestBody, &responseBody)
+	entry.RequestBody, entry.ResponseBody = requestBody, responseBody
+	return entry, err
+}
+
+// DeleteRequestLogsBefore removes request logs created before the given timestamp in milliseconds
+func (r *RequestLogDB) DeleteRequestLogsBefore(timestamp int64) (int64, error) {
+	query := `DELETE FROM request_logs WHERE created_at < ?`
+	result, err := r.DB.Exec(query, timestamp)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanRequestLog scans the requestLogColumns of a row followed by any extra destinations
+func scanRequestLog(row rowScanner, extra ...interface{}) (models.RequestLog, error) {
+	var entry models.RequestLog
+	var attempts string
+	dest := []interface{}{&entry.ID, &entry.CreatedAt, &entry.RequestID, &entry.Method, &entry.Path, &entry.Query, &entry.ClientIP, &entry.UserAgent, &entry.APIKeyID, &entry.Alias, &entry.UpstreamModel, &entry.AccountID, &entry.AccountName, &attempts, &entry.Status, &entry.LatencyMs, &entry.PromptTokens, &entry.CompletionTokens}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal([]byte(attempts), &entry.Attempts); err != nil || entry.Attempts == nil {
+		entry.Attempts = []models.RequestAttempt{}
+	}
+	return entry, nil
+}
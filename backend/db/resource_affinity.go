@@ -0,0 +1,44 @@
+package db
+
+import (
+	"air_router/utils/common"
+	"database/sql"
+)
+
+// ResourceAffinityDB represents the database operations for the accounts owning stored upstream objects
+type ResourceAffinityDB struct {
+	DB *sql.DB
+}
+
+// SetResourceAccount records the account that created a resource
+func (r *ResourceAffinityDB) SetResourceAccount(resourceID string, accountID int, kind string) error {
+	query := `INSERT INTO resource_affinity (resource_id, account_id, kind, created_at) VALUES (?, ?, ?, ?)
+	ON CONFLICT(resource_id) DO UPDATE SET account_id = excluded.account_id, kind = excluded.kind`
+	_, err := r.DB.Exec(query, resourceID, accountID, kind, common.GetCurrentTimestamp())
+	return err
+}
+
+// GetResourceAccount returns the ID of the account that created a resource, sql.ErrNoRows if unknown
+func (r *ResourceAffinityDB) GetResourceAccount(resourceID string) (int, error) {
+	var accountID int
+	query := `SELECT account_id FROM resource_affinity WHERE resource_id = ?`
+	err := r.DB.QueryRow(query, resourceID).Scan(&accountID)
+	return accountID, err
+}
+
+// DeleteResource forgets a resource deleted upstream
+func (r *ResourceAffinityDB) DeleteResource(resourceID string) error {
+	query := `DELETE FROM resource_affinity WHERE resource_id = ?`
+	_, err := r.DB.Exec(query, resourceID)
+	return err
+}
+
+// DeleteResourcesBefore removes resources recorded before the given timestamp in milliseconds
+func (r *ResourceAffinityDB) DeleteResourcesBefore(timestamp int64) (int64, error) {
+	query := `DELETE FROM resource_affinity WHERE created_at < ?`
+	result, err := r.DB.Exec(query, timestamp)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
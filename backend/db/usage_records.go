@@ -0,0 +1,97 @@
+package db
+
+import (
+	"air_router/models"
+	"database/sql"
+	"strings"
+)
+
+// UsageRecordDB represents the database operations for per-request usage records
+type UsageRecordDB struct {
+	DB *sql.DB
+}
+
+// CreateUsageRecord inserts the usage record of a proxied request
+func (u *UsageRecordDB) CreateUsageRecord(record models.UsageRecord) error {
+	query := `INSERT INTO usage_records (created_at, api_key_id, account_id, alias, upstream_model, path, prompt_tokens, completion_tokens, cached_tokens, cost, latency_ms, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := u.DB.Exec(query, record.CreatedAt, record.APIKeyID, record.AccountID, record.Alias, record.UpstreamModel, record.Path, record.PromptTokens, record.CompletionTokens, record.CachedTokens, record.Cost, record.LatencyMs, record.Status)
+	return err
+}
+
+// GetAccountSpend returns the cost recorded for an account since the given timestamp in milliseconds
+func (u *UsageRecordDB) GetAccountSpend(accountID int, since int64) (float64, error) {
+	var spend float64
+	query := `SELECT COALESCE(SUM(cost), 0) FROM usage_records WHERE account_id = ? AND created_at >= ?`
+	err := u.DB.QueryRow(query, accountID, since).Scan(&spend)
+	return spend, err
+}
+
+// usageGroupColumns maps each summary dimension to its selected columns
+var usageGroupColumns = map[string][]string{
+	models.UsageGroupDay:     {`strftime('%Y-%m-%d', u.created_at / 1000, 'unixepoch')`},
+	models.UsageGroupAccount: {`u.account_id`, `COALESCE(a.name, '')`},
+	models.UsageGroupAlias:   {`u.alias`},
+	models.UsageGroupAPIKey:  {`u.api_key_id`, `COALESCE(k.name, '')`},
+	models.UsageGroupModel:   {`u.upstream_model`},
+}
+
+// usageGroupFields returns the scan destinations matching usageGroupColumns
+func usageGroupFields(summary *models.UsageSummary, group string) []interface{} {
+	switch group {
+	case models.UsageGroupDay:
+		return []interface{}{&summary.Day}
+	case models.UsageGroupAccount:
+		summary.AccountID, summary.AccountName = new(int), new(string)
+		return []interface{}{summary.AccountID, summary.AccountName}
+	case models.UsageGroupAlias:
+		summary.Alias = new(string)
+		return []interface{}{summary.Alias}
+	case models.UsageGroupAPIKey:
+		summary.APIKeyID, summary.APIKeyName = new(int), new(string)
+		return []interface{}{summary.APIKeyID, summary.APIKeyName}
+	case models.UsageGroupModel:
+		summary.UpstreamModel = new(string)
+		return []interface{}{summary.UpstreamModel}
+	}
+	return nil
+}
+
+// GetUsageSummary aggregates the usage records created in [from, to) by the given dimensions
+// Timestamps are in milliseconds and days are UTC
+func (u *UsageRecordDB) GetUsageSummary(groupBy []string, from, to int64) ([]models.UsageSummary, error) {
+	var columns, groups []string
+	for _, group := range groupBy {
+		columns = append(columns, usageGroupColumns[group]...)
+		groups = append(groups, usageGroupColumns[group][0])
+	}
+	columns = append(columns, `COUNT(*)`, `COALESCE(SUM(u.prompt_tokens), 0)`, `COALESCE(SUM(u.completion_tokens), 0)`, `COALESCE(SUM(u.cached_tokens), 0)`, `COALESCE(SUM(u.cost), 0)`)
+
+	query := `SELECT ` + strings.Join(columns, ", ") + ` FROM usage_records u
+	LEFT JOIN accounts a ON a.id = u.account_id
+	LEFT JOIN api_keys k ON k.id = u.api_key_id
+	WHERE u.created_at >= ? AND u.created_at < ?`
+	if len(groups) > 0 {
+		query += ` GROUP BY ` + strings.Join(groups, ", ") + ` ORDER BY ` + strings.Join(groups, ", ")
+	}
+
+	rows, err := u.DB.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.UsageSummary{}
+	for rows.Next() {
+		var summary models.UsageSummary
+		var fields []interface{}
+		for _, group := range groupBy {
+			fields = append(fields, usageGroupFields(&summary, group)...)
+		}
+		fields = append(fields, &summary.Requests, &summary.PromptTokens, &summary.CompletionTokens, &summary.CachedTokens, &summary.Cost)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
@@ -7,6 +7,7 @@ import (
 	"air_router/cache"
 	"air_router/db"
 	"air_router/models"
+	"air_router/services"
 	"air_router/utils"
 	"air_router/utils/common"
 
@@ -16,12 +17,14 @@ import (
 type AccountHandler struct {
 	AccountDB *db.AccountDB
 	ModelDB   *db.ModelDB
+	Budgets   *services.BudgetGuard
 }
 
-func NewAccountHandler(accountDB *db.AccountDB, modelDB *db.ModelDB) *AccountHandler {
+func NewAccountHandler(accountDB *db.AccountDB, modelDB *db.ModelDB, budgets *services.BudgetGuard) *AccountHandler {
 	return &AccountHandler{
 		AccountDB: accountDB,
 		ModelDB:   modelDB,
+		Budgets:   budgets,
 	}
 }
 
@@ -35,6 +38,9 @@ func (h *AccountHandler) GetAccounts(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range accounts {
+		setPausedReason(&accounts[i])
+	}
 
 	c.JSON(http.StatusOK, utils.BuildPaginatedResponse(accounts, total, params.Page, params.PageSize, params.Search))
 }
@@ -51,6 +57,16 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		account.Enabled = true
 	}
 
+	applyAccountDefaults(&account)
+	if !common.ValidateAccountProtocol(account.Protocol) {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidProtocol, common.ErrTypeInvalidProtocol)
+		return
+	}
+	if account.Budget < 0 || !common.ValidateBudgetPeriod(account.BudgetPeriod) {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidBudget, common.ErrTypeValidation)
+		return
+	}
+
 	id, err := h.AccountDB.CreateAccount(account)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -58,6 +74,8 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 	}
 
 	account.ID = int(id)
+	h.Budgets.Evaluate(account)
+	setPausedReason(&account)
 
 	// Trigger cache refresh asynchronously
 	go cache.RefreshModelsCache(h.AccountDB, h.ModelDB)
@@ -83,10 +101,12 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 		return
 	}
 
+	setPausedReason(&account)
 	common.SendJSONResponse(c, http.StatusOK, account)
 }
 
 // UpdateAccount handles PUT /api/accounts/:id
+// Fields omitted from the request body keep their stored values
 func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 	id, err := common.ParseIDParam(c, "id")
 	if err != nil {
@@ -94,18 +114,40 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	var account models.Account
+	account, err := h.AccountDB.GetAccount(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			common.SendAPIError(c, http.StatusNotFound, common.ErrMsgAccountNotFound, common.ErrTypeNotFound)
+		} else {
+			common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		}
+		return
+	}
+
 	if err := c.ShouldBindJSON(&account); err != nil {
 		common.SendAPIError(c, http.StatusBadRequest, "Invalid parameters: "+err.Error(), common.ErrTypeInvalidRequest)
 		return
 	}
 
 	account.ID = id
+	applyAccountDefaults(&account)
+	if !common.ValidateAccountProtocol(account.Protocol) {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidProtocol, common.ErrTypeInvalidProtocol)
+		return
+	}
+	if account.Budget < 0 || !common.ValidateBudgetPeriod(account.BudgetPeriod) {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidBudget, common.ErrTypeValidation)
+		return
+	}
 	if err := h.AccountDB.UpdateAccount(account); err != nil {
 		common.SendAPIError(c, http.StatusBadRequest, err.Error(), common.ErrTypeBadRequest)
 		return
 	}
 
+	// A changed budget may pause or resume the account right away
+	h.Budgets.Evaluate(account)
+	setPausedReason(&account)
+
 	// Trigger cache refresh asynchronously
 	go cache.RefreshModelsCache(h.AccountDB, h.ModelDB)
 
@@ -124,6 +166,7 @@ func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
 		return
 	}
+	cache.ResumeAccount(id)
 
 	// Trigger cache refresh asynchronously
 	go cache.RefreshModelsCache(h.AccountDB, h.ModelDB)
@@ -154,6 +197,8 @@ func (h *AccountHandler) ToggleAccount(c *gin.Context) {
 		return
 	}
 
+	setPausedReason(&account)
+
 	// Trigger cache refresh asynchronously
 	go cache.RefreshModelsCache(h.AccountDB, h.ModelDB)
 
@@ -193,3 +238,37 @@ func (h *AccountHandler) GetAccountModels(c *gin.Context) {
 		"total":      len(models),
 	})
 }
+
+// applyAccountDefaults replaces invalid weights and timeouts with their defaults
+func applyAccountDefaults(account *models.Account) {
+	if account.BudgetPeriod == "" {
+		account.BudgetPeriod = models.DefaultBudgetPeriod
+	}
+	if account.Weight <= 0 {
+		account.Weight = 1
+	}
+	if account.CostMultiplier <= 0 {
+		account.CostMultiplier = 1
+	}
+	if account.ConnectTimeoutMs < 0 {
+		account.ConnectTimeoutMs = 0
+	}
+	if account.FirstByteTimeoutMs < 0 {
+		account.FirstByteTimeoutMs = 0
+	}
+	if account.IdleTimeoutMs < 0 {
+		account.IdleTimeoutMs = 0
+	}
+	if account.Protocol == "" {
+		account.Protocol = models.DefaultAccountProtocol
+	}
+	// Anthropic-native accounts always serve the Claude API
+	if account.Protocol == models.ProtocolAnthropic {
+		account.ClaudeAvailable = true
+	}
+}
+
+// setPausedReason reports why an enabled account is currently excluded from routing
+func setPausedReason(account *models.Account) {
+	account.PausedReason, _ = cache.AccountPauseReason(account.ID)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"air_router/db"
+	"air_router/models"
+	"air_router/services"
+	"air_router/utils"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	APIKeyDB    *db.APIKeyDB
+	RateLimiter *services.RateLimiter
+}
+
+func NewAPIKeyHandler(apiKeyDB *db.APIKeyDB, rateLimiter *services.RateLimiter) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyDB:    apiKeyDB,
+		RateLimiter: rateLimiter,
+	}
+}
+
+// GetAPIKeys handles GET /api/keys with pagination and search support
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	params := utils.ParsePaginationParams(c)
+
+	apiKeys, total, err := h.APIKeyDB.GetPaginatedAPIKeys(params.Page, params.PageSize, params.Search)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	// Ensure we return an empty array instead of null when no keys exist
+	if apiKeys == nil {
+		apiKeys = []models.APIKey{}
+	}
+
+	c.JSON(http.StatusOK, utils.BuildPaginatedResponse(apiKeys, total, params.Page, params.PageSize, params.Search))
+}
+
+// CreateAPIKey handles POST /api/keys
+// A random key is generated when the request does not provide one
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var apiKey models.APIKey
+	if err := c.ShouldBindJSON(&apiKey); err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, "Invalid parameters: "+err.Error(), common.ErrTypeInvalidRequest)
+		return
+	}
+
+	apiKey.Name = strings.TrimSpace(apiKey.Name)
+	if apiKey.Name == "" {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgAPIKeyNameRequired, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	if apiKey.Key == "" {
+		key, err := common.GenerateAPIKey()
+		if err != nil {
+			common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+			return
+		}
+		apiKey.Key = key
+	}
+
+	if !apiKey.Enabled && apiKey.ID == 0 {
+		apiKey.Enabled = true
+	}
+
+	id, err := h.APIKeyDB.CreateAPIKey(apiKey)
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, err.Error(), common.ErrTypeBadRequest)
+		return
+	}
+
+	apiKey.ID = int(id)
+	common.SendJSONResponse(c, http.StatusCreated, apiKey)
+}
+
+// GetAPIKey handles GET /api/keys/:id
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	apiKey, err := h.APIKeyDB.GetAPIKey(id)
+	if err != nil {
+		h.sendLookupError(c, err)
+		return
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, apiKey)
+}
+
+// UpdateAPIKey handles PUT /api/keys/:id
+// Fields omitted from the request body keep their stored values
+func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	apiKey, err := h.APIKeyDB.GetAPIKey(id)
+	if err != nil {
+		h.sendLookupError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&apiKey); err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, "Invalid parameters: "+err.Error(), common.ErrTypeInvalidRequest)
+		return
+	}
+
+	apiKey.ID = id
+	apiKey.Name = strings.TrimSpace(apiKey.Name)
+	if apiKey.Name == "" {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgAPIKeyNameRequired, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	if err := h.APIKeyDB.UpdateAPIKey(apiKey); err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, err.Error(), common.ErrTypeBadRequest)
+		return
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, apiKey)
+}
+
+// DeleteAPIKey handles DELETE /api/keys/:id
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	if err := h.APIKeyDB.DeleteAPIKey(id); err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ToggleAPIKey handles PATCH /api/keys/:id
+func (h *APIKeyHandler) ToggleAPIKey(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	if err := h.APIKeyDB.ToggleAPIKey(id); err != nil {
+		h.sendLookupError(c, err)
+		return
+	}
+
+	apiKey, err := h.APIKeyDB.GetAPIKey(id)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, apiKey)
+}
+
+// GetAPIKeysUsage handles GET /api/keys/usage
+// Returns the current consumption of every key against its limits
+func (h *APIKeyHandler) GetAPIKeysUsage(c *gin.Context) {
+	apiKeys, err := h.APIKeyDB.GetAPIKeys()
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	usageList := make([]models.APIKeyUsage, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		usage, err := h.RateLimiter.GetUsage(apiKey)
+		if err != nil {
+			common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+			return
+		}
+		usageList = append(usageList, usage)
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, gin.H{
+		"data":  usageList,
+		"total": len(usageList),
+	})
+}
+
+// GetAPIKeyUsage handles GET /api/keys/:id/usage
+func (h *APIKeyHandler) GetAPIKeyUsage(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	apiKey, err := h.APIKeyDB.GetAPIKey(id)
+	if err != nil {
+		h.sendLookupError(c, err)
+		return
+	}
+
+	usage, err := h.RateLimiter.GetUsage(apiKey)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, usage)
+}
+
+// sendLookupError sends the proper error response for a failed api key lookup
+func (h *APIKeyHandler) sendLookupError(c *gin.Context, err error) {
+	if err == sql.ErrNoRows {
+		common.SendAPIError(c, http.StatusNotFound, common.ErrMsgAPIKeyNotFound, common.ErrTypeNotFound)
+		return
+	}
+	common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+}
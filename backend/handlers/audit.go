@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"air_router/constants"
+	"air_router/models"
+	"air_router/services"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLog returns a middleware that writes a request log for every proxied request
+// It runs after API key authentication, so only authenticated requests are logged
+func AuditLog(audit *services.AuditLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if audit == nil || !audit.Enabled {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		var requestBody, responseBody *bodyCapture
+		if audit.Bodies {
+			requestBody = &bodyCapture{limit: audit.MaxBodyBytes}
+			c.Request.Body = &teeReadCloser{Reader: io.TeeReader(c.Request.Body, requestBody), Closer: c.Request.Body}
+			responseBody = &bodyCapture{limit: audit.MaxBodyBytes}
+			c.Writer = &auditResponseWriter{ResponseWriter: c.Writer, capture: responseBody}
+		}
+
+		c.Next()
+
+		usage := getResponseUsage(c)
+		entry := models.RequestLog{
+			CreatedAt:        common.GetCurrentTimestamp(),
+			RequestID:        c.GetString(constants.ContextKeyRequestID),
+			Method:           c.Request.Method,
+			Path:             c.Request.URL.Path,
+			Query:            services.RedactQuery(c.Request.URL.RawQuery),
+			ClientIP:         c.ClientIP(),
+			UserAgent:        c.Request.UserAgent(),
+			Alias:            c.GetString(constants.ContextKeyAlias),
+			UpstreamModel:    c.GetString(constants.ContextKeyUpstreamModel),
+			Attempts:         []models.RequestAttempt{},
+			Status:           c.Writer.Status(),
+			LatencyMs:        time.Since(start).Milliseconds(),
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		}
+		if value, ok := c.Get(constants.ContextKeyAPIKey); ok {
+			entry.APIKeyID = value.(models.APIKey).ID
+		}
+		if value, ok := c.Get(constants.ContextKeyAccount); ok {
+			account := value.(models.Account)
+			entry.AccountID, entry.AccountName = account.ID, account.Name
+		}
+		if value, ok := c.Get(constants.ContextKeyAttempts); ok {
+			entry.Attempts = value.([]models.RequestAttempt)
+		}
+		if audit.Bodies {
+			entry.RequestBody = audit.LoggableBody(c.ContentType(), requestBody.buf.Bytes(), requestBody.size)
+			entry.ResponseBody = audit.LoggableBody(c.Writer.Header().Get("Content-Type"), responseBody.buf.Bytes(), responseBody.size)
+		}
+
+		audit.Record(entry)
+	}
+}
+
+// bodyCapture keeps the first limit bytes written to it and counts the rest
+type bodyCapture struct {
+	buf   bytes.Buffer
+	limit int
+	size  int64
+}
+
+func (b *bodyCapture) Write(p []byte) (int, error) {
+	b.size += int64(len(p))
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// teeReadCloser copies a request body into a capture as the handler reads it
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// auditResponseWriter copies the relayed response body into a capture
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	capture *bodyCapture
+}
+
+func (w *auditResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.capture.Write(p[:n])
+	return n, err
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.capture.Write([]byte(s[:n]))
+	return n, err
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"air_router/constants"
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyAuth returns a middleware that validates router-issued API keys on /v1 routes
+// Both "Authorization: Bearer <key>" and "X-Api-Key: <key>" styles are accepted
+// Set DISABLE_API_KEY_AUTH=true to skip validation entirely
+func APIKeyAuth(apiKeyDB *db.APIKeyDB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if common.GetEnvOrDefault("DISABLE_API_KEY_AUTH", "false") == "true" {
+			c.Next()
+			return
+		}
+
+		key := extractClientAPIKey(c)
+		if key == "" {
+			common.SendAPIError(c, http.StatusUnauthorized, common.ErrMsgAPIKeyMissing, common.ErrTypeUnauthorized)
+			c.Abort()
+			return
+		}
+
+		apiKey, err := apiKeyDB.GetAPIKeyByKey(key)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				slog.ErrorContext(c, "error looking up API key", "error", err)
+				common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgAPIKeyCheckFailed, common.ErrTypeInternalServer)
+			} else {
+				common.SendAPIError(c, http.StatusUnauthorized, common.ErrMsgInvalidAPIKey, common.ErrTypeUnauthorized)
+			}
+			c.Abort()
+			return
+		}
+
+		if !apiKey.Enabled {
+			common.SendAPIError(c, http.StatusUnauthorized, common.ErrMsgInvalidAPIKey, common.ErrTypeUnauthorized)
+			c.Abort()
+			return
+		}
+
+		c.Set(constants.ContextKeyAPIKey, apiKey)
+		c.Next()
+	}
+}
+
+// extractClientAPIKey extracts the client key from the Authorization, X-Api-Key or x-goog-api-key header,
+// or from the key query parameter used by Gemini clients
+func extractClientAPIKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+	}
+	for _, header := range []string{"X-Api-Key", "x-goog-api-key"} {
+		if key := strings.TrimSpace(c.GetHeader(header)); key != "" {
+			return key
+		}
+	}
+	return strings.TrimSpace(c.Query("key"))
+}
+
+// getClientAPIKey returns the validated client API key stored by APIKeyAuth
+func getClientAPIKey(c *gin.Context) (models.APIKey, bool) {
+	value, exists := c.Get(constants.ContextKeyAPIKey)
+	if !exists {
+		return models.APIKey{}, false
+	}
+	apiKey, ok := value.(models.APIKey)
+	return apiKey, ok
+}
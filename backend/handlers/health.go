@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+
+	"air_router/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	DB *sql.DB
+
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(db *sql.DB) *HealthHandler {
+	return &HealthHandler{
+		DB: db,
+	}
+}
+
+// SetShuttingDown makes /readyz fail so load balancers stop sending new requests while in-flight ones drain
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// HandleHealthz handles GET /healthz, reporting that the process is up
+func (h *HealthHandler) HandleHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// HandleReadyz handles GET /readyz, reporting whether requests can be routed:
+// a models cache refresh has succeeded, the database answers and no shutdown is in progress
+func (h *HealthHandler) HandleReadyz(c *gin.Context) {
+	reason := ""
+	switch {
+	case h.shuttingDown.Load():
+		reason = "shutting down"
+	case cache.GetLastRefreshStatus().LoadedAt.IsZero():
+		reason = "models cache not loaded yet"
+	case h.DB.PingContext(c.Request.Context()) != nil:
+		reason = "database unavailable"
+	}
+
+	if reason != "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reason": reason})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
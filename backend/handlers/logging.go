@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"log/slog"
+	"time"
+
+	"air_router/constants"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID returns a middleware that assigns every request an ID, taken from X-Request-ID when the
+// client sent a valid one, and echoes it back so the request's logs can be correlated
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := common.RequestIDOrGenerate(c.GetHeader(constants.RequestIDHeader))
+		c.Set(constants.ContextKeyRequestID, requestID)
+		c.Header(constants.RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// AccessLog returns a middleware that logs every request once its response has been sent
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+		slog.Log(c, level, "request completed", attrs...)
+	}
+}
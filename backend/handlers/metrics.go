@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"air_router/constants"
+	"air_router/models"
+	"air_router/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMetrics returns a middleware that records the traffic metrics of every proxied request
+func RequestMetrics(metrics *services.ProxyMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		writer := &metricsResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		var accountName string
+		if value, ok := c.Get(constants.ContextKeyAccount); ok {
+			accountName = value.(models.Account).Name
+		}
+		var attempts []models.RequestAttempt
+		if value, ok := c.Get(constants.ContextKeyAttempts); ok {
+			attempts = value.([]models.RequestAttempt)
+		}
+		var firstByte time.Duration
+		if !writer.firstByte.IsZero() {
+			firstByte = writer.firstByte.Sub(start)
+		}
+
+		metrics.ObserveRequest(c.GetString(constants.ContextKeyAlias), c.GetString(constants.ContextKeyUpstreamModel), accountName, c.Writer.Status(), attempts, time.Since(start), firstByte)
+	}
+}
+
+// metricsResponseWriter remembers when the first byte of the response was relayed
+type metricsResponseWriter struct {
+	gin.ResponseWriter
+	firstByte time.Time
+}
+
+func (w *metricsResponseWriter) markFirstByte() {
+	if w.firstByte.IsZero() {
+		w.firstByte = time.Now()
+	}
+}
+
+func (w *metricsResponseWriter) Write(p []byte) (int, error) {
+	w.markFirstByte()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *metricsResponseWriter) WriteString(s string) (int, error) {
+	w.markFirstByte()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *metricsResponseWriter) WriteHeaderNow() {
+	w.markFirstByte()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// HandleMetrics handles GET /metrics in the Prometheus text exposition format
+func HandleMetrics(c *gin.Context) {
+	var buf bytes.Buffer
+	services.Metrics.WritePrometheus(&buf)
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
@@ -66,6 +66,18 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 		return
 	}
 
+	// Validate routing strategy, falling back to the default when omitted
+	if model.Strategy == "" {
+		model.Strategy = air_router_models.DefaultRoutingStrategy
+	}
+	if !air_router_utils.ValidateRoutingStrategy(model.Strategy) {
+		air_router_utils.SendAPIError(c, http.StatusBadRequest, air_router_utils.ErrMsgInvalidStrategy, air_router_utils.ErrTypeInvalidStrategy)
+		return
+	}
+	if model.HedgeDelayMs < 0 {
+		model.HedgeDelayMs = 0
+	}
+
 	// Set default enabled status
 	if model.Enabled == false {
 		model.Enabled = true
@@ -82,6 +94,7 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 }
 
 // UpdateModel updates an existing model
+// Fields omitted from the request body keep their stored values
 func (h *ModelHandler) UpdateModel(c *gin.Context) {
 	id, err := air_router_utils.ParseIDParam(c, "id")
 	if err != nil {
@@ -89,7 +102,12 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 		return
 	}
 
-	var model air_router_models.Model
+	model, err := h.modelDB.GetModel(id)
+	if err != nil {
+		air_router_utils.SendAPIError(c, http.StatusNotFound, air_router_utils.ErrMsgModelNotFound, air_router_utils.ErrTypeNotFound)
+		return
+	}
+
 	if err := c.ShouldBindJSON(&model); err != nil {
 		air_router_utils.SendAPIError(c, http.StatusBadRequest, err.Error(), air_router_utils.ErrTypeInvalidRequest)
 		return
@@ -101,6 +119,18 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 		return
 	}
 
+	// Validate routing strategy
+	if model.Strategy == "" {
+		model.Strategy = air_router_models.DefaultRoutingStrategy
+	}
+	if !air_router_utils.ValidateRoutingStrategy(model.Strategy) {
+		air_router_utils.SendAPIError(c, http.StatusBadRequest, air_router_utils.ErrMsgInvalidStrategy, air_router_utils.ErrTypeInvalidStrategy)
+		return
+	}
+	if model.HedgeDelayMs < 0 {
+		model.HedgeDelayMs = 0
+	}
+
 	model.ID = id
 	err = h.modelDB.UpdateModel(model)
 	if err != nil {
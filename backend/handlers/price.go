@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PriceHandler struct {
+	PriceDB *db.PriceDB
+}
+
+func NewPriceHandler(priceDB *db.PriceDB) *PriceHandler {
+	return &PriceHandler{
+		PriceDB: priceDB,
+	}
+}
+
+// GetPrices handles GET /api/prices
+func (h *PriceHandler) GetPrices(c *gin.Context) {
+	prices, err := h.PriceDB.GetPrices()
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	// Ensure we return an empty array instead of null when no prices exist
+	if prices == nil {
+		prices = []models.ModelPrice{}
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, gin.H{
+		"data":  prices,
+		"total": len(prices),
+	})
+}
+
+// CreatePrice handles POST /api/prices
+func (h *PriceHandler) CreatePrice(c *gin.Context) {
+	var price models.ModelPrice
+	if err := c.ShouldBindJSON(&price); err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, "Invalid parameters: "+err.Error(), common.ErrTypeInvalidRequest)
+		return
+	}
+
+	if !validatePrice(c, &price) {
+		return
+	}
+
+	id, err := h.PriceDB.CreatePrice(price)
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, err.Error(), common.ErrTypeBadRequest)
+		return
+	}
+
+	price, err = h.PriceDB.GetPrice(int(id))
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+	common.SendJSONResponse(c, http.StatusCreated, price)
+}
+
+// GetPrice handles GET /api/prices/:id
+func (h *PriceHandler) GetPrice(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	price, err := h.PriceDB.GetPrice(id)
+	if err != nil {
+		h.sendLookupError(c, err)
+		return
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, price)
+}
+
+// UpdatePrice handles PUT /api/prices/:id
+// Fields omitted from the request body keep their stored values
+func (h *PriceHandler) UpdatePrice(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	price, err := h.PriceDB.GetPrice(id)
+	if err != nil {
+		h.sendLookupError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&price); err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, "Invalid parameters: "+err.Error(), common.ErrTypeInvalidRequest)
+		return
+	}
+
+	price.ID = id
+	if !validatePrice(c, &price) {
+		return
+	}
+
+	if err := h.PriceDB.UpdatePrice(price); err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, err.Error(), common.ErrTypeBadRequest)
+		return
+	}
+
+	price, err = h.PriceDB.GetPrice(id)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgFailedToUpdate, common.ErrTypeInternalServer)
+		return
+	}
+	common.SendJSONResponse(c, http.StatusOK, price)
+}
+
+// DeletePrice handles DELETE /api/prices/:id
+func (h *PriceHandler) DeletePrice(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	if err := h.PriceDB.DeletePrice(id); err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// validatePrice normalizes a price and sends a validation error when it is invalid
+func validatePrice(c *gin.Context, price *models.ModelPrice) bool {
+	price.ModelID = strings.TrimSpace(price.ModelID)
+	if price.ModelID == "" {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgPriceModelRequired, common.ErrTypeInvalidRequest)
+		return false
+	}
+	if price.InputPrice < 0 || price.OutputPrice < 0 || price.CachedInputPrice < 0 {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidPrice, common.ErrTypeValidation)
+		return false
+	}
+	return true
+}
+
+// sendLookupError sends the proper error response for a failed price lookup
+func (h *PriceHandler) sendLookupError(c *gin.Context, err error) {
+	if err == sql.ErrNoRows {
+		common.SendAPIError(c, http.StatusNotFound, common.ErrMsgPriceNotFound, common.ErrTypeNotFound)
+		return
+	}
+	common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+}
@@ -1,18 +1,23 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 
 	"air_router/cache"
+	"air_router/constants"
 	"air_router/db"
 	"air_router/models"
 	"air_router/services"
+	"air_router/translator"
 	"air_router/utils"
 	"air_router/utils/common"
 
@@ -20,57 +25,26 @@ import (
 )
 
 type ProxyHandler struct {
-	AccountDB *db.AccountDB
-	ModelDB   *db.ModelDB
-}
-
-// We'll use the existing globalAccountCounter from services package
-
-// failedAccountsCache stores failed account IDs and their failure timestamps
-var failedAccountsCache = make(map[int]int64)
-var failedAccountsMutex sync.RWMutex
-
-// isAccountFailed checks if an account is in the failed cache and if it's within 10 minutes
-func isAccountFailed(accountID int) bool {
-	failedAccountsMutex.RLock()
-	defer failedAccountsMutex.RUnlock()
-
-	if timestamp, exists := failedAccountsCache[accountID]; exists {
-		// Check if the failure is within 10 minutes
-		if time.Now().Unix()-timestamp < 10*60 {
-			return true
-		}
-	}
-	return false
-}
-
-// addFailedAccount adds an account to the failed cache
-func addFailedAccount(accountID int) {
-	failedAccountsMutex.Lock()
-	defer failedAccountsMutex.Unlock()
-
-	failedAccountsCache[accountID] = time.Now().Unix()
-}
-
-// removeFailedAccount removes an account from the failed cache
-func removeFailedAccount(accountID int) {
-	failedAccountsMutex.Lock()
-	defer failedAccountsMutex.Unlock()
-
-	delete(failedAccountsCache, accountID)
+	AccountDB   *db.AccountDB
+	ModelDB     *db.ModelDB
+	RateLimiter *services.RateLimiter
+	Affinity    *services.ResourceAffinity
+	UsageDB     *db.UsageRecordDB
+	Pricing     *services.Pricing
+	Budgets     *services.BudgetGuard
 }
 
 // NewProxyHandler creates a new ProxyHandler
-func NewProxyHandler(accountDB *db.AccountDB, modelDB *db.ModelDB) *ProxyHandler {
-	handler := &ProxyHandler{
-		AccountDB: accountDB,
-		ModelDB:   modelDB,
+func NewProxyHandler(accountDB *db.AccountDB, modelDB *db.ModelDB, rateLimiter *services.RateLimiter, affinity *services.ResourceAffinity, usageDB *db.UsageRecordDB, pricing *services.Pricing, budgets *services.BudgetGuard) *ProxyHandler {
+	return &ProxyHandler{
+		AccountDB:   accountDB,
+		ModelDB:     modelDB,
+		RateLimiter: rateLimiter,
+		Affinity:    affinity,
+		UsageDB:     usageDB,
+		Pricing:     pricing,
+		Budgets:     budgets,
 	}
-
-	// Start the background task to refresh models cache
-	go cache.StartModelsCacheTask(accountDB, modelDB)
-
-	return handler
 }
 
 // extractModelID extracts model id from request body
@@ -94,15 +68,16 @@ func (h *ProxyHandler) HandleProxy(c *gin.Context) {
 		HandleModels(c, h.ModelDB)
 		return
 	}
+	if modelID, ok := strings.CutPrefix(strings.TrimPrefix(path, "/"), "models/"); ok && c.Request.Method == http.MethodGet {
+		HandleModel(c, h.ModelDB, modelID)
+		return
+	}
 
-	// Check USE_ALL_IN_ONE environment variable using common function
-	useAllInOne := common.GetEnvOrDefault("USE_ALL_IN_ONE", "true")
-
-	if useAllInOne == "true" {
-		// New logic for all-in-one mode
-		h.handleAllInOneProxy(c, path)
+	finish, ok := h.admitRequest(c, "/v1"+path)
+	if !ok {
 		return
 	}
+	defer finish()
 
 	// Read request body
 	bodyBytes, err := io.ReadAll(c.Request.Body)
@@ -110,10 +85,47 @@ func (h *ProxyHandler) HandleProxy(c *gin.Context) {
 		common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgFailedToReadBody, common.ErrTypeInternalServer)
 		return
 	}
-	// log.Printf("[Proxy /v1/%s] Request body: %s", path, string(bodyBytes))
+
+	// Uploads are multipart forms whose model, if any, is a form field
+	boundary, isMultipart := utils.MultipartBoundary(c.GetHeader("Content-Type"))
+	var fields map[string]string
+	if isMultipart {
+		if fields, err = utils.MultipartFields(bodyBytes, boundary, "model", "stream"); err != nil {
+			common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgFailedToParseBody, common.ErrTypeInvalidRequest)
+			return
+		}
+	}
+
+	// Requests naming no model, such as GET /v1/files/{id}, file uploads or batch creation, go to any account
+	modeless := len(bytes.TrimSpace(bodyBytes)) == 0
+	if isMultipart {
+		modeless = fields["model"] == ""
+	} else if services.IsResourcePath(path) {
+		modeless = modeless || common.ExtractModelID(bodyBytes) == ""
+	}
+	if modeless {
+		h.handleModelessProxy(c, path, bodyBytes)
+		return
+	}
+
+	// Check USE_ALL_IN_ONE environment variable using common function
+	useAllInOne := common.GetEnvOrDefault("USE_ALL_IN_ONE", "true")
+
+	if useAllInOne == "true" {
+		// New logic for all-in-one mode
+		if isMultipart {
+			h.handleMultipartAliasProxy(c, path, bodyBytes, boundary, fields)
+			return
+		}
+		h.handleAllInOneProxy(c, path, bodyBytes)
+		return
+	}
 
 	// Extract model id using common function
 	modelID := common.ExtractModelID(bodyBytes)
+	if isMultipart {
+		modelID = fields["model"]
+	}
 
 	if modelID == "" {
 		// No model id specified, return error
@@ -121,9 +133,73 @@ func (h *ProxyHandler) HandleProxy(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[Proxy /v1/%s] Model ID: %s", path, modelID)
+	slog.InfoContext(c, "proxying request", "route", "/v1"+path, "model", modelID)
+	h.handleDirectProxy(c, path, modelID, bodyBytes)
+}
+
+// admitRequest enforces the per-key rate limits and token quotas before any upstream attempt
+// and starts the deadline budget; the returned function records the usage once the response was relayed
+func (h *ProxyHandler) admitRequest(c *gin.Context, route string) (func(), bool) {
+	start := time.Now()
+	apiKey, hasAPIKey := getClientAPIKey(c)
+	if hasAPIKey {
+		result := h.RateLimiter.Allow(apiKey)
+		if !result.Allowed {
+			slog.InfoContext(c, "request rate limited", "route", route, "api_key", apiKey.Name, "api_key_id", apiKey.ID, "reason", result.Message)
+			sendRateLimitError(c, result)
+			return nil, false
+		}
+	}
+
+	// Every attempt of this request shares one deadline budget
+	services.Retry.StartBudget(c)
+
+	finish := func() {
+		usage := getResponseUsage(c)
+		if hasAPIKey {
+			h.RateLimiter.RecordUsage(apiKey, usage.TotalTokens(), int64(c.GetInt(constants.ContextKeyHedges)))
+		}
+		h.recordUsage(c, route, apiKey.ID, usage, time.Since(start))
+	}
+	return finish, true
+}
+
+// recordUsage writes the usage record of a relayed request
+func (h *ProxyHandler) recordUsage(c *gin.Context, route string, apiKeyID int, usage utils.Usage, latency time.Duration) {
+	if h.UsageDB == nil {
+		return
+	}
+
+	record := models.UsageRecord{
+		CreatedAt:        common.GetCurrentTimestamp(),
+		APIKeyID:         apiKeyID,
+		Alias:            c.GetString(constants.ContextKeyAlias),
+		UpstreamModel:    c.GetString(constants.ContextKeyUpstreamModel),
+		Path:             route,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CachedTokens:     usage.CachedTokens,
+		LatencyMs:        latency.Milliseconds(),
+		Status:           c.Writer.Status(),
+	}
+	var account models.Account
+	if value, ok := c.Get(constants.ContextKeyAccount); ok {
+		account = value.(models.Account)
+		record.AccountID = account.ID
+		record.Cost = h.Pricing.Cost(account, record.UpstreamModel, usage)
+	}
+
+	if err := h.UsageDB.CreateUsageRecord(record); err != nil {
+		slog.ErrorContext(c, "error recording usage", "route", route, "error", err)
+		return
+	}
+	h.Budgets.Charge(account, record.Cost)
+}
+
+// handleDirectProxy forwards a request for an upstream model ID to the accounts serving it
+func (h *ProxyHandler) handleDirectProxy(c *gin.Context, path string, modelID string, bodyBytes []byte) {
 	// Try to forward using accounts that support the model
-	proxyService := services.NewProxyService()
+	proxyService := services.NewProxyService(h.AccountDB, h.Affinity)
 	success, lastResp, lastBody := proxyService.TryWithRetryModel(c, path, modelID, bodyBytes)
 	if success {
 		return
@@ -131,14 +207,12 @@ func (h *ProxyHandler) HandleProxy(c *gin.Context) {
 
 	// Return the last failed response
 	if lastResp != nil {
-		// Copy response headers
-		for key, values := range lastResp.Header {
-			for _, value := range values {
-				c.Header(key, value)
-			}
-		}
-		c.Status(lastResp.StatusCode)
-		c.Data(lastResp.StatusCode, lastResp.Header.Get("Content-Type"), lastBody)
+		relayResponse(c, lastResp, lastBody)
+		return
+	}
+
+	if services.BudgetExhausted(c) {
+		common.SendAPIError(c, http.StatusGatewayTimeout, common.ErrMsgDeadlineExceeded, common.ErrTypeForward)
 		return
 	}
 
@@ -146,16 +220,100 @@ func (h *ProxyHandler) HandleProxy(c *gin.Context) {
 	common.SendAPIError(c, http.StatusNotFound, fmt.Sprintf(common.ErrMsgNoAccountsFound, modelID), common.ErrTypeNotFound)
 }
 
-// handleAllInOneProxy handles proxy requests in all-in-one mode with retry logic
-func (h *ProxyHandler) handleAllInOneProxy(c *gin.Context, path string) {
-	// Read request body
-	bodyBytes, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgFailedToReadBody, common.ErrTypeInternalServer)
+// handleModelessProxy forwards a request that names no model, such as GET /v1/files/{id} or a file upload
+// Any enabled account may own the resource, so a 404 moves on to the next account without counting as a failure
+func (h *ProxyHandler) handleModelessProxy(c *gin.Context, path string, bodyBytes []byte) {
+	accounts := services.FilterAccountsForPath(cache.GetRoutableAccounts(), path, c.Request.Header)
+
+	// Objects such as files and batches only exist on the account that created them
+	accounts, pinned := h.Affinity.Pin(c, accounts, path, bodyBytes)
+
+	// Claude requests without a model cannot be translated, they need accounts speaking the Claude API
+	if !pinned && services.IsClaudeAPI(path, c.Request.Header) {
+		accounts = services.ClaudeAccounts(accounts)
+	}
+	if len(accounts) == 0 {
+		common.SendAPIError(c, http.StatusNotFound, fmt.Sprintf(common.ErrMsgNoAccountsForPath, path), common.ErrTypeNotFound)
 		return
 	}
-	// log.Printf("[Proxy /v1/%s] All-in-one mode - Request body: %s", path, string(bodyBytes))
 
+	upstreamPath := path
+	query := c.Request.URL.Query()
+	// The client's router key must not reach the upstream
+	query.Del("key")
+	if encoded := query.Encode(); encoded != "" {
+		upstreamPath += "?" + encoded
+	}
+
+	proxyService := services.NewProxyService(h.AccountDB, h.Affinity)
+	balancer := services.GetBalancer(models.DefaultRoutingStrategy)
+	maxAttempts := services.Retry.Attempts(services.Retry.MaxAttempts, len(accounts))
+	triedAccounts := make(map[int]bool)
+
+	var lastResp *http.Response
+	var lastRespBody []byte
+	for attempt := 0; attempt < maxAttempts && !services.BudgetExhausted(c); attempt++ {
+		// The circuit of the account itself is used, no model is involved
+		account, ok := services.SelectAccount(accounts, triedAccounts, balancer, "", "")
+		if !ok {
+			break
+		}
+		slog.InfoContext(c, "model-less attempt", "route", "/v1"+path, "attempt", attempt+1, "max_attempts", maxAttempts, "account", account.Name, "account_id", account.ID)
+
+		circuitKey := services.CircuitKey{AccountID: account.ID}
+		resp, success, respBody := proxyService.TryWithAccount(c, account, upstreamPath, bodyBytes, c.Request.Header)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			// The resource most likely belongs to another account
+			services.AccountBreaker.Release(circuitKey)
+			services.NoteAttempt(c, account, "", resp, services.FailureClient)
+			lastResp, lastRespBody = resp, respBody
+			continue
+		}
+		failure := proxyService.RecordAttempt(c, account, circuitKey, resp)
+		if resp == nil {
+			if !failure.Retryable() {
+				break
+			}
+			continue
+		}
+		lastResp, lastRespBody = resp, respBody
+
+		if !success {
+			if !failure.Retryable() {
+				break
+			}
+			continue
+		}
+
+		h.Affinity.Track(c, account, c.Request.Method, path, resp)
+		defer resp.Body.Close()
+		services.SetRoutingHeaders(c)
+		c.Set(constants.ContextKeyUsage, utils.StreamResponse(c, resp))
+		slog.InfoContext(c, "model-less request served", "route", "/v1"+path, "account", account.Name, "account_id", account.ID)
+		return
+	}
+
+	if lastResp != nil {
+		relayResponse(c, lastResp, lastRespBody)
+		return
+	}
+	if services.BudgetExhausted(c) {
+		common.SendAPIError(c, http.StatusGatewayTimeout, common.ErrMsgDeadlineExceeded, common.ErrTypeForward)
+		return
+	}
+	common.SendAPIError(c, http.StatusBadGateway, common.ErrMsgAllAttemptsFailed, common.ErrTypeForward)
+}
+
+// relayResponse sends a failed upstream response whose body was already read
+func relayResponse(c *gin.Context, resp *http.Response, body []byte) {
+	services.SetRoutingHeaders(c)
+	utils.CopyResponseHeaders(c, resp.Header)
+	c.Status(resp.StatusCode)
+	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+}
+
+// handleAllInOneProxy handles JSON proxy requests in all-in-one mode with retry logic
+func (h *ProxyHandler) handleAllInOneProxy(c *gin.Context, path string, bodyBytes []byte) {
 	// Parse request body to map[string]interface{}
 	var requestBody map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &requestBody); err != nil {
@@ -170,7 +328,35 @@ func (h *ProxyHandler) handleAllInOneProxy(c *gin.Context, path string) {
 		return
 	}
 
-	log.Printf("[Proxy /v1/%s] All-in-one mode - Requested model ID: %s", path, modelID)
+	isStream, _ := requestBody["stream"].(bool)
+
+	// Update request body with the actual model ID
+	rewrite := func(upstreamModelID string) (string, []byte, error) {
+		requestBody["model"] = upstreamModelID
+		body, err := json.Marshal(requestBody)
+		return path, body, err
+	}
+	h.proxyAlias(c, "/v1"+path, modelID, isStream, rewrite)
+}
+
+// handleMultipartAliasProxy handles multipart uploads such as audio transcriptions and image edits in all-in-one mode
+// The body is kept as received and only its model field is replaced for each upstream model
+func (h *ProxyHandler) handleMultipartAliasProxy(c *gin.Context, path string, bodyBytes []byte, boundary string, fields map[string]string) {
+	isStream := fields["stream"] == "true"
+	rewrite := func(upstreamModelID string) (string, []byte, error) {
+		body, err := utils.RewriteMultipartField(bodyBytes, boundary, "model", upstreamModelID)
+		return path, body, err
+	}
+	h.proxyAlias(c, "/v1"+path, fields["model"], isStream, rewrite)
+}
+
+// aliasRewrite returns the upstream path and body of a request for the upstream model picked for its alias
+type aliasRewrite func(upstreamModelID string) (path string, body []byte, err error)
+
+// proxyAlias resolves an alias model and races its accounts, bounded by the retry policy
+// route is the client path used in logs
+func (h *ProxyHandler) proxyAlias(c *gin.Context, route string, modelID string, isStream bool, rewrite aliasRewrite) {
+	slog.InfoContext(c, "proxying alias request", "route", route, "alias", modelID)
 
 	// Get actual model IDs from database based on requested model ID
 	model, err := h.ModelDB.GetModelByModelID(modelID)
@@ -179,6 +365,8 @@ func (h *ProxyHandler) handleAllInOneProxy(c *gin.Context, path string) {
 		return
 	}
 
+	c.Set(constants.ContextKeyAlias, model.ModelID)
+
 	// Check if model is enabled
 	if !model.Enabled {
 		common.SendAPIError(c, http.StatusNotFound, fmt.Sprintf("Model '%s' is disabled", modelID), common.ErrTypeNotFound)
@@ -192,135 +380,204 @@ func (h *ProxyHandler) handleAllInOneProxy(c *gin.Context, path string) {
 		return
 	}
 
-	// Randomly select one actual model ID using global counter
-	selectedModelID := h.getRandomModelID(actualModelIDs)
-	//log.Printf("[Proxy /v1/%s] All-in-one mode - Selected actual model ID: %s from %s", path, selectedModelID, model.ModelID)
+	// Pick the upstream models to try using the alias's routing strategy
+	balancer := services.GetBalancer(model.Strategy)
+	candidates := services.CandidateModels(balancer, model.ModelID, actualModelIDs)
+
+	state := &aliasAttempts{}
+	for i, candidate := range candidates {
+		if !h.proxyAliasModel(c, route, model, balancer, candidate, i == len(candidates)-1, isStream, rewrite, state) {
+			break
+		}
+	}
+
+	// Served, answered with an error, or the client went away
+	if c.Writer.Written() || c.Request.Context().Err() != nil {
+		return
+	}
+
+	// All attempts failed - return the last response or generic error
+	if state.lastResp != nil {
+		relayResponse(c, state.lastResp, state.lastRespBody)
+		return
+	}
+
+	// The deadline budget ran out before any account answered
+	if services.BudgetExhausted(c) {
+		common.SendAPIError(c, http.StatusGatewayTimeout, common.ErrMsgDeadlineExceeded, common.ErrTypeForward)
+		return
+	}
+
+	// Every circuit was open, nothing was attempted
+	if state.attempts == 0 {
+		common.SendAPIError(c, http.StatusServiceUnavailable, fmt.Sprintf(common.ErrMsgAllAccountsUnavailable, state.modelID), common.ErrTypeForward)
+		return
+	}
+
+	// No responses at all
+	common.SendAPIError(c, http.StatusBadGateway, common.ErrMsgAllAttemptsFailed, common.ErrTypeForward)
+}
 
+// aliasAttempts is the progress of an alias request across the upstream models it tries
+type aliasAttempts struct {
+	modelID      string // last upstream model tried
+	attempts     int    // hedges included, bounded by the retry policy for the whole request
+	hedges       int
+	lastResp     *http.Response
+	lastRespBody []byte
+}
+
+// proxyAliasModel tries the accounts of one upstream model of an alias
+// It returns true when the next upstream model may be tried, false once a response was written or retrying is pointless
+// Errors that leave nothing to try for this model are only sent when it is the last one
+func (h *ProxyHandler) proxyAliasModel(c *gin.Context, route string, model models.Model, balancer services.Balancer, selectedModelID string, last bool, isStream bool, rewrite aliasRewrite, state *aliasAttempts) bool {
 	// Check if selectedModelID is a pattern (ends with *)
 	if len(selectedModelID) > 0 && selectedModelID[len(selectedModelID)-1] == '*' {
 		// Use pattern matching to get actual model ID from cache
 		actualSelectedModelID, err := cache.GetRandomModelIDByPattern(selectedModelID)
 		if err != nil {
-			log.Printf("[Proxy /v1/%s] All-in-one mode - Pattern matching failed: %v", path, err)
+			slog.WarnContext(c, "model pattern matching failed", "route", route, "error", err)
+			if !last {
+				return true
+			}
 			common.SendAPIError(c, http.StatusNotFound, fmt.Sprintf("Pattern '%s' matching failed: %s", selectedModelID, err.Error()), common.ErrTypeNotFound)
-			return
+			return false
 		}
-		log.Printf("[Proxy /v1/%s] All-in-one mode - Pattern '%s' resolved to actual model ID: %s", path, selectedModelID, actualSelectedModelID)
+		slog.InfoContext(c, "model pattern resolved", "route", route, "pattern", selectedModelID, "model", actualSelectedModelID)
 		selectedModelID = actualSelectedModelID
 	}
+	state.modelID = selectedModelID
 
-	// Get accounts that support the selected model ID
-	accounts := cache.GetAccountsForModel(selectedModelID)
+	upstreamPath, updatedBodyBytes, err := rewrite(selectedModelID)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgFailedToUpdateBody, common.ErrTypeInternalServer)
+		return false
+	}
+
+	// Get accounts that support the selected model ID and speak the protocol of the path
+	accounts := services.FilterAccountsForPath(cache.GetAccountsForModel(selectedModelID), upstreamPath, c.Request.Header)
 	if len(accounts) == 0 {
+		if !last {
+			slog.WarnContext(c, "no accounts for upstream model, trying the next one", "route", route, "model", selectedModelID)
+			return true
+		}
 		common.SendAPIError(c, http.StatusNotFound, fmt.Sprintf(common.ErrMsgNoAccountsFound, selectedModelID), common.ErrTypeNotFound)
-		return
+		return false
 	}
 
-	// Try up to 3 times with different accounts
-	var lastResp *http.Response
-	var lastRespBody []byte
-	maxAttempts := 3
-	if len(accounts) < 3 {
-		maxAttempts = len(accounts)
-	}
-
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		// Select an account, skipping failed ones
-		var selectedAccount models.Account
-		accountFound := false
-
-		// Try to find a non-failed account
-		for i := 0; i < len(accounts); i++ {
-			tempAccount := h.getRandomAccount(accounts)
-			if !isAccountFailed(tempAccount.ID) {
-				selectedAccount = tempAccount
-				accountFound = true
-				break
-			}
-		}
+	// Requests referencing a stored object go to the account that created it
+	accounts, _ = h.Affinity.Pin(c, accounts, upstreamPath, updatedBodyBytes)
+
+	// Try several accounts, bounded by what the retry policy leaves of the request's attempts
+	maxAttempts := services.Retry.Attempts(services.Retry.MaxAttempts-state.attempts, len(accounts))
+
+	// Accounts already considered for this model are never retried
+	triedAccounts := make(map[int]bool)
+
+	proxyService := services.NewProxyService(h.AccountDB, h.Affinity)
+	proxyRequest := services.ProxyRequest{
+		Provider: model.Provider,
+		ModelID:  selectedModelID,
+		Path:     upstreamPath,
+		Body:     updatedBodyBytes,
+		Stream:   isStream,
+	}
 
-		// If all accounts are failed, just use the randomly selected one
+	for attempt := 0; attempt < maxAttempts && !services.BudgetExhausted(c); attempt++ {
+		// Pick within the highest priority tier whose circuits still let requests through
+		selectedAccount, accountFound := services.SelectAccount(accounts, triedAccounts, balancer, model.ModelID, selectedModelID)
 		if !accountFound {
-			selectedAccount = h.getRandomAccount(accounts)
+			break
 		}
+		state.attempts++
 
-		log.Printf("[Proxy /v1/%s] All-in-one mode - Attempt %d/%d with account: %s (ID: %d),model:%s", path, attempt+1, maxAttempts, selectedAccount.Name, selectedAccount.ID, selectedModelID)
+		slog.InfoContext(c, "alias attempt", "route", route, "attempt", attempt+1, "max_attempts", maxAttempts, "account", selectedAccount.Name, "account_id", selectedAccount.ID, "model", selectedModelID)
 
-		// Update request body with the actual model ID
-		requestBody["model"] = selectedModelID
-		updatedBodyBytes, err := json.Marshal(requestBody)
-		if err != nil {
-			common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgFailedToUpdateBody, common.ErrTypeInternalServer)
-			return
+		// Hedge to another account when the alias enables it and an attempt is left for it
+		var hedgeDelay time.Duration
+		if model.HedgeDelayMs > 0 && attempt+1 < maxAttempts {
+			hedgeDelay = time.Duration(model.HedgeDelayMs) * time.Millisecond
+		}
+		nextAccount := func() (models.Account, bool) {
+			account, ok := services.SelectAccount(accounts, triedAccounts, balancer, model.ModelID, selectedModelID)
+			if ok {
+				attempt++
+				state.attempts++
+				state.hedges++
+			}
+			return account, ok
 		}
 
 		// Forward request using the selected account
-		proxyService := services.NewProxyService()
-		resp, success, respBody := proxyService.TryWithAccount(c, selectedAccount, path, updatedBodyBytes, c.Request.Header)
-
-		if resp != nil {
-			// Keep track of last response for error reporting
-			lastResp = resp
-			lastRespBody = respBody
-
-			if success {
-				// Success! Remove from failed cache if it was there, then stream response and return
-				defer resp.Body.Close()
-				removeFailedAccount(selectedAccount.ID)
-				utils.StreamResponse(c, resp)
-				log.Printf("[Proxy /v1/%s] All-in-one mode - Success with account %s (ID: %d)", path, selectedAccount.BaseURL, selectedAccount.ID)
-				return
+		winner, failedLegs := proxyService.RaceAccounts(c, proxyRequest, selectedAccount, hedgeDelay, nextAccount)
+		c.Set(constants.ContextKeyHedges, state.hedges)
+
+		retryable := true
+		for _, leg := range failedLegs {
+			failure := proxyService.RecordLeg(c, leg)
+			services.AccountStats.End(leg.Account.ID)
+			if leg.Resp != nil {
+				// Keep track of last response for error reporting
+				state.lastResp = leg.Resp
+				state.lastRespBody = leg.Body
+				slog.WarnContext(c, "alias attempt failed", "route", route, "account", leg.Account.Name, "account_id", leg.Account.ID, "status", leg.Resp.StatusCode, "failure", failure)
 			} else {
-				// Failed - add to failed cache and try next account
-				defer resp.Body.Close()
-				addFailedAccount(selectedAccount.ID)
-				log.Printf("[Proxy /v1/%s] All-in-one mode - Failed with account %s (ID: %d)", path, selectedAccount.Name, selectedAccount.ID)
+				// No response, or a stream too slow to start - counted against the pair circuit
+				slog.WarnContext(c, "no response from account", "route", route, "account", leg.Account.Name, "account_id", leg.Account.ID, "failure", failure)
 			}
-		} else {
-			// No response - add to failed cache and try next account
-			addFailedAccount(selectedAccount.ID)
-			log.Printf("[Proxy /v1/%s] All-in-one mode - No response from account %s (ID: %d)", path, selectedAccount.Name, selectedAccount.ID)
+			retryable = retryable && failure.Retryable()
 		}
-	}
 
-	// All attempts failed - return the last response or generic error
-	if lastResp != nil {
-		// Copy response headers
-		for key, values := range lastResp.Header {
-			for _, value := range values {
-				c.Header(key, value)
+		if winner != nil {
+			// Success! Stream response and return
+			proxyService.RecordLeg(c, winner)
+			h.Affinity.Track(c, winner.Account, c.Request.Method, upstreamPath, winner.Resp)
+			defer winner.Release()
+			defer services.AccountStats.End(winner.Account.ID)
+			if model.RewriteModel {
+				translator.RewriteModel(winner.Resp, model.ModelID)
 			}
+			services.SetRoutingHeaders(c)
+			c.Set(constants.ContextKeyUsage, utils.StreamResponse(c, winner.Resp))
+			slog.InfoContext(c, "alias request served", "route", route, "account", winner.Account.Name, "account_id", winner.Account.ID, "hedged", winner.Hedged)
+			return false
+		}
+
+		// The client's own error is relayed as-is without trying other accounts or models
+		if !retryable {
+			return false
 		}
-		c.Status(lastResp.StatusCode)
-		c.Data(lastResp.StatusCode, lastResp.Header.Get("Content-Type"), lastRespBody)
-		return
 	}
 
-	// No responses at all
-	common.SendAPIError(c, http.StatusBadGateway, common.ErrMsgAllAttemptsFailed, common.ErrTypeForward)
+	return !services.BudgetExhausted(c) && state.attempts < services.Retry.MaxAttempts
 }
 
-// getRandomModelID randomly selects a model ID from the list using global counter
-func (h *ProxyHandler) getRandomModelID(modelIDs []string) string {
-	if len(modelIDs) == 0 {
-		return ""
+// getResponseUsage returns the token usage recorded for the relayed response
+func getResponseUsage(c *gin.Context) utils.Usage {
+	value, exists := c.Get(constants.ContextKeyUsage)
+	if !exists {
+		return utils.Usage{}
 	}
-	if len(modelIDs) == 1 {
-		return modelIDs[0]
-	}
-
-	index := common.GetRandomIndex(len(modelIDs))
-	return modelIDs[index]
+	usage, _ := value.(utils.Usage)
+	return usage
 }
 
-// getRandomAccount randomly selects an account from the list using global counter
-func (h *ProxyHandler) getRandomAccount(accounts []models.Account) models.Account {
-	return common.GetRandomElement(accounts)
+// sendRateLimitError sends a 429 response with Retry-After and x-ratelimit-* headers
+func sendRateLimitError(c *gin.Context, result services.RateLimitResult) {
+	for key, value := range result.Headers {
+		c.Header(key, value)
+	}
+	retryAfter := int64(math.Ceil(result.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	common.SendAPIError(c, http.StatusTooManyRequests, result.Message, common.ErrTypeRateLimit)
 }
 
 // forwardRequest forwards the request to the selected account
 func (h *ProxyHandler) forwardRequest(c *gin.Context, path string, modelID string, bodyBytes []byte, account models.Account) {
-	proxyService := services.NewProxyService()
+	proxyService := services.NewProxyService(h.AccountDB, h.Affinity)
 
 	// Use TryWithAccount directly since we already have the specific account
 	resp, success, respBody := proxyService.TryWithAccount(c, account, path, bodyBytes, c.Request.Header)
@@ -328,17 +585,12 @@ func (h *ProxyHandler) forwardRequest(c *gin.Context, path string, modelID strin
 		defer resp.Body.Close()
 		if success {
 			// Stream response
-			utils.StreamResponse(c, resp)
-			log.Printf("[ProxyService] Success with account %s (ID: %d)", account.BaseURL, account.ID)
+			c.Set(constants.ContextKeyUsage, utils.StreamResponse(c, resp))
+			slog.InfoContext(c, "request served", "account", account.Name, "account_id", account.ID)
 			return
 		} else {
 			// Return the failed response
-			// Copy response headers
-			for key, values := range resp.Header {
-				for _, value := range values {
-					c.Header(key, value)
-				}
-			}
+			utils.CopyResponseHeaders(c, resp.Header)
 			c.Status(resp.StatusCode)
 			c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
 			return
@@ -374,6 +626,7 @@ func (h *ProxyHandler) HandleDebugModels(c *gin.Context) {
 			"owned_by":                 modelInfo.OwnedBy,
 			"supported_endpoint_types": modelInfo.SupportedEndpointTypes,
 			"account_list":             accs,
+			"account_health":           buildAccountHealth(modelID, accs),
 		}
 
 		if len(modelInfo.CompatibleProviders) > 0 {
@@ -390,6 +643,23 @@ func (h *ProxyHandler) HandleDebugModels(c *gin.Context) {
 	})
 }
 
+// buildAccountHealth returns the circuit state of every account serving an upstream model
+func buildAccountHealth(modelID string, accounts []models.Account) []map[string]interface{} {
+	health := make([]map[string]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		status := services.AccountBreaker.Status(services.CircuitKey{AccountID: account.ID, ModelID: modelID})
+		health = append(health, map[string]interface{}{
+			"account_id":   account.ID,
+			"account_name": account.Name,
+			"state":        status.State,
+			"failures":     status.Failures,
+			"trips":        status.Trips,
+			"open_until":   status.OpenUntil,
+		})
+	}
+	return health
+}
+
 // HandleReloadModels manually triggers models cache refresh
 func (h *ProxyHandler) HandleReloadModels(c *gin.Context) {
 	// Execute refresh asynchronously
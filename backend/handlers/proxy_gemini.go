@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"air_router/cache"
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGeminiProxy handles /v1beta/:path proxy requests in the Gemini native format
+// The model is part of the path (models/{model}:{method}) instead of the body
+func (h *ProxyHandler) HandleGeminiProxy(c *gin.Context) {
+	path := c.Param("path")
+
+	if c.Request.Method == http.MethodGet && strings.Trim(path, "/") == "models" {
+		handleGeminiModels(c, h.ModelDB)
+		return
+	}
+
+	modelID, method, ok := parseGeminiModelPath(path)
+	if !ok {
+		common.SendAPIError(c, http.StatusNotFound, fmt.Sprintf(common.ErrMsgUnsupportedGeminiPath, path), common.ErrTypeNotFound)
+		return
+	}
+
+	route := "/v1beta" + path
+	finish, ok := h.admitRequest(c, route)
+	if !ok {
+		return
+	}
+	defer finish()
+
+	// Read request body
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, common.ErrMsgFailedToReadBody, common.ErrTypeInternalServer)
+		return
+	}
+
+	// Only alt=sse streams are SSE, plain streamGenerateContent returns a JSON array
+	query := c.Request.URL.Query()
+	isStream := method == "streamGenerateContent" && query.Get("alt") == "sse"
+	// The client's router key must not reach the upstream
+	query.Del("key")
+
+	if common.GetEnvOrDefault("USE_ALL_IN_ONE", "true") == "true" {
+		rewrite := func(upstreamModelID string) (string, []byte, error) {
+			return geminiModelPath(upstreamModelID, method, query), bodyBytes, nil
+		}
+		h.proxyAlias(c, route, modelID, isStream, rewrite)
+		return
+	}
+
+	slog.InfoContext(c, "proxying request", "route", route, "model", modelID)
+	h.handleDirectProxy(c, geminiModelPath(modelID, method, query), modelID, bodyBytes)
+}
+
+// parseGeminiModelPath splits a Gemini path /models/{model}:{method} into its model and method
+func parseGeminiModelPath(path string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(path, "/models/")
+	if !ok {
+		return "", "", false
+	}
+	index := strings.LastIndex(rest, ":")
+	if index <= 0 || index == len(rest)-1 {
+		return "", "", false
+	}
+	return rest[:index], rest[index+1:], true
+}
+
+// geminiModelPath builds the upstream Gemini path for a model, keeping the client query such as alt=sse
+func geminiModelPath(modelID string, method string, query url.Values) string {
+	path := "/models/" + modelID + ":" + method
+	if encoded := query.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+	return path
+}
+
+// handleGeminiModels handles GET /v1beta/models with the models list in the Gemini format
+func handleGeminiModels(c *gin.Context, modelDB *db.ModelDB) {
+	var modelList []cache.ModelInfo
+	if common.GetEnvOrDefault("USE_ALL_IN_ONE", "true") == "true" {
+		modelList = buildAllInOneModelList(models.ProviderGemini, modelDB)
+	} else {
+		modelList = buildGeminiModelList()
+	}
+
+	geminiModels := make([]gin.H, 0, len(modelList))
+	for _, model := range modelList {
+		displayName := model.DisplayName
+		if displayName == "" {
+			displayName = model.ID
+		}
+		geminiModels = append(geminiModels, gin.H{
+			"name":                       "models/" + model.ID,
+			"displayName":                displayName,
+			"supportedGenerationMethods": []string{"generateContent", "streamGenerateContent", "countTokens"},
+		})
+	}
+
+	slog.InfoContext(c, "models list served", "api", "gemini", "models", len(geminiModels))
+	c.JSON(http.StatusOK, gin.H{"models": geminiModels})
+}
+
+// buildGeminiModelList builds and returns the models served by Gemini-native accounts
+func buildGeminiModelList() []cache.ModelInfo {
+	modelList := make([]cache.ModelInfo, 0)
+	for modelID, modelInfo := range cache.GetAllModelInfos() {
+		for _, account := range cache.GetAccountsForModel(modelID) {
+			if account.Protocol == models.ProtocolGemini {
+				modelList = append(modelList, *modelInfo)
+				break
+			}
+		}
+	}
+
+	sortModelsByID(modelList)
+	return modelList
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"sort"
@@ -28,7 +28,7 @@ func HandleModels(c *gin.Context, modelDB *db.ModelDB) {
 
 	// Check if this is a Claude API request (X-Api-Key header present)
 	if c.GetHeader("X-Api-Key") != "" {
-		log.Printf("[Models] Claude API detected (X-Api-Key present)")
+		slog.DebugContext(c, "Claude models list requested (X-Api-Key present)")
 		handleClaudeModels(c)
 		return
 	}
@@ -37,23 +37,48 @@ func HandleModels(c *gin.Context, modelDB *db.ModelDB) {
 	handleOpenAIModels(c)
 }
 
+// HandleModel handles GET /v1/models/{id}, answered from the aliases or the cached upstream models
+func HandleModel(c *gin.Context, modelDB *db.ModelDB, modelID string) {
+	if common.GetEnvOrDefault("USE_ALL_IN_ONE", "true") == "true" {
+		model, err := modelDB.GetModelByModelID(modelID)
+		if err != nil || !model.Enabled {
+			common.SendAPIError(c, http.StatusNotFound, common.ErrMsgModelNotFound, common.ErrTypeNotFound)
+			return
+		}
+		c.JSON(http.StatusOK, cache.ModelInfo{
+			ID:                     model.ModelID,
+			Object:                 "model",
+			OwnedBy:                "air_router",
+			SupportedEndpointTypes: []string{"openai"},
+		})
+		return
+	}
+
+	modelInfo, ok := cache.GetAllModelInfos()[modelID]
+	if !ok {
+		common.SendAPIError(c, http.StatusNotFound, common.ErrMsgModelNotFound, common.ErrTypeNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, modelInfo)
+}
+
 // handleAllInOneModels handles models request in all-in-one mode
 func handleAllInOneModels(c *gin.Context, modelDB *db.ModelDB) {
 	// Check if X-Api-Key header is present to determine provider
 	if c.GetHeader("X-Api-Key") != "" {
-		log.Printf("[Models] All-in-one mode: Claude API detected (X-Api-Key present)")
+		slog.DebugContext(c, "all-in-one Claude models list requested (X-Api-Key present)")
 		handleAllInOneClaudeModels(c, modelDB)
 		return
 	}
 
-	log.Printf("[Models] All-in-one mode: Chat API detected (no X-Api-Key)")
+	slog.DebugContext(c, "all-in-one chat models list requested (no X-Api-Key)")
 	handleAllInOneChatModels(c, modelDB)
 }
 
 // handleAllInOneClaudeModels handles Claude models in all-in-one mode
 func handleAllInOneClaudeModels(c *gin.Context, modelDB *db.ModelDB) {
 	modelList := buildAllInOneModelList(models.ProviderClaude, modelDB)
-	log.Printf("[Models] All-in-one response: %d Claude models", len(modelList))
+	slog.InfoContext(c, "models list served", "api", "claude", "all_in_one", true, "models", len(modelList))
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":    modelList,
@@ -65,7 +90,7 @@ func handleAllInOneClaudeModels(c *gin.Context, modelDB *db.ModelDB) {
 // handleAllInOneChatModels handles Chat models in all-in-one mode
 func handleAllInOneChatModels(c *gin.Context, modelDB *db.ModelDB) {
 	modelList := buildAllInOneModelList(models.ProviderChat, modelDB)
-	log.Printf("[Models] All-in-one response: %d Chat models", len(modelList))
+	slog.InfoContext(c, "models list served", "api", "chat", "all_in_one", true, "models", len(modelList))
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":    modelList,
@@ -84,7 +109,7 @@ func buildAllInOneModelList(provider models.Provider, modelDB *db.ModelDB) []cac
 	// Get enabled models for the specific provider from the database
 	enabledModels, err := modelDB.GetEnabledModelsByProvider(provider)
 	if err != nil {
-		log.Printf("[Models] Error getting enabled models for provider %s from database: %v", provider, err)
+		slog.Error("error getting enabled models from database", "provider", provider, "error", err)
 		return modelList
 	}
 
@@ -109,7 +134,7 @@ func buildAllInOneModelList(provider models.Provider, modelDB *db.ModelDB) []cac
 // handleClaudeModels handles Claude API requests (X-Api-Key present)
 func handleClaudeModels(c *gin.Context) {
 	modelList := buildClaudeModelList()
-	log.Printf("[Models] Response: %d Claude models for Claude API", len(modelList))
+	slog.InfoContext(c, "models list served", "api", "claude", "models", len(modelList))
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":    modelList,
@@ -128,10 +153,6 @@ func handleOpenAIModels(c *gin.Context) {
 		disableClaude = false
 	}
 
-	// Read request body for logging
-	// bodyBytes, _ := io.ReadAll(c.Request.Body)
-	// log.Printf("[Models] Request body: %s", string(bodyBytes))
-
 	modelList := buildOpenAIModelList(disableClaude)
 
 	response := gin.H{
@@ -140,7 +161,7 @@ func handleOpenAIModels(c *gin.Context) {
 		"success": true,
 	}
 
-	log.Printf("[Models] Response: %d models (OpenAI API)", len(modelList))
+	slog.InfoContext(c, "models list served", "api", "openai", "models", len(modelList))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -218,7 +239,7 @@ func buildOpenAIModelList(disableClaude bool) []cache.ModelInfo {
 	for _, modelInfo := range modelInfos {
 		// Skip Claude models if disabled
 		if disableClaude && isClaudeModel(modelInfo.ID) {
-			log.Printf("[Models] Skipped model due to DISABLE_CLAUDE: %s", modelInfo.ID)
+			slog.Debug("model skipped due to DISABLE_CLAUDE", "model", modelInfo.ID)
 			continue
 		}
 
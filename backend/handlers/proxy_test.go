@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"air_router/cache"
+	"air_router/db"
+	"air_router/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestModelessProxyDropsRouterKeyFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var upstreamQuery string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/models" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": []map[string]string{{"id": "gpt-4o"}}})
+			return
+		}
+		upstreamQuery = r.URL.RawQuery
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	defer upstream.Close()
+
+	conn, err := db.InitDB(filepath.Join(t.TempDir(), "accounts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	accountDB := &db.AccountDB{DB: conn}
+	modelDB := &db.ModelDB{DB: conn}
+	account := models.Account{Name: "upstream", BaseURL: upstream.URL, APIKey: "upstream-key", Enabled: true, Protocol: models.DefaultAccountProtocol, Weight: 1, CostMultiplier: 1, BudgetPeriod: models.DefaultBudgetPeriod}
+	if _, err := accountDB.CreateAccount(account); err != nil {
+		t.Fatal(err)
+	}
+	cache.RefreshModelsCache(accountDB, modelDB)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/files?key=router-secret&purpose=batch", nil)
+
+	handler := &ProxyHandler{AccountDB: accountDB, ModelDB: modelDB}
+	handler.handleModelessProxy(c, "/files", nil)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if upstreamQuery != "purpose=batch" {
+		t.Fatalf("expected the upstream query to keep only purpose, got %q", upstreamQuery)
+	}
+}
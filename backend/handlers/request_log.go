@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RequestLogHandler struct {
+	RequestLogDB *db.RequestLogDB
+}
+
+func NewRequestLogHandler(requestLogDB *db.RequestLogDB) *RequestLogHandler {
+	return &RequestLogHandler{
+		RequestLogDB: requestLogDB,
+	}
+}
+
+// GetRequestLogs handles GET /api/logs with pagination, search and
+// request_id, status, account_id, api_key_id, alias, from and to (YYYY-MM-DD, inclusive) filters
+func (h *RequestLogHandler) GetRequestLogs(c *gin.Context) {
+	params := utils.ParsePaginationParams(c)
+
+	var filter models.RequestLogFilter
+	var err error
+	for name, target := range map[string]*int{"status": &filter.Status, "account_id": &filter.AccountID, "api_key_id": &filter.APIKeyID} {
+		if value := c.Query(name); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil {
+				common.SendAPIError(c, http.StatusBadRequest, fmt.Sprintf(common.ErrMsgInvalidLogFilter, name), common.ErrTypeInvalidRequest)
+				return
+			}
+		}
+	}
+	filter.RequestID = c.Query("request_id")
+	filter.Alias = c.Query("alias")
+
+	from, fromErr := parseUsageDate(c.Query("from"), time.Time{})
+	to, toErr := parseUsageDate(c.Query("to"), time.Time{})
+	if fromErr != nil || toErr != nil || (!from.IsZero() && !to.IsZero() && to.Before(from)) {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidDateRange, common.ErrTypeInvalidRequest)
+		return
+	}
+	if !from.IsZero() {
+		filter.From = from.UnixMilli()
+	}
+	if !to.IsZero() {
+		filter.To = to.AddDate(0, 0, 1).UnixMilli()
+	}
+
+	entries, total, err := h.RequestLogDB.GetPaginatedRequestLogs(params.Page, params.PageSize, params.Search, filter)
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.BuildPaginatedResponse(entries, total, params.Page, params.PageSize, params.Search))
+}
+
+// GetRequestLog handles GET /api/logs/:id, including the stored bodies
+func (h *RequestLogHandler) GetRequestLog(c *gin.Context) {
+	id, err := common.ParseIDParam(c, "id")
+	if err != nil {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidID, common.ErrTypeInvalidRequest)
+		return
+	}
+
+	entry, err := h.RequestLogDB.GetRequestLog(int64(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			common.SendAPIError(c, http.StatusNotFound, common.ErrMsgRequestLogNotFound, common.ErrTypeNotFound)
+		} else {
+			common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		}
+		return
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, entry)
+}
@@ -2,13 +2,20 @@ package handlers
 
 import (
 	air_router_db "air_router/db"
+	"air_router/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupWebRouter creates the web interface router with frontend and API routes
-func SetupWebRouter(indexHandler *IndexHandler, accountHandler *AccountHandler, modelHandler *ModelHandler, proxyHandler *ProxyHandler, frontendPath string) *gin.Engine {
-	router := gin.Default()
+func SetupWebRouter(indexHandler *IndexHandler, accountHandler *AccountHandler, modelHandler *ModelHandler, apiKeyHandler *APIKeyHandler, proxyHandler *ProxyHandler, priceHandler *PriceHandler, usageHandler *UsageHandler, requestLogHandler *RequestLogHandler, healthHandler *HealthHandler, frontendPath string) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// Probes are registered ahead of the access log so they do not flood it
+	router.GET("/healthz", healthHandler.HandleHealthz)
+	router.GET("/readyz", healthHandler.HandleReadyz)
+	router.Use(AccessLog())
 
 	// Serve static files
 	router.Static("/static", frontendPath)
@@ -16,6 +23,9 @@ func SetupWebRouter(indexHandler *IndexHandler, accountHandler *AccountHandler,
 	// Serve index page
 	router.GET("/", indexHandler.ServeIndex)
 
+	// Prometheus metrics of the proxy
+	router.GET("/metrics", HandleMetrics)
+
 	// Serve debug page
 	router.GET("/debug", func(c *gin.Context) {
 		c.File(frontendPath + "/debug.html")
@@ -46,6 +56,35 @@ func SetupWebRouter(indexHandler *IndexHandler, accountHandler *AccountHandler,
 			models.GET("/search", modelHandler.SearchModels)
 		}
 
+		keys := api.Group("/keys")
+		{
+			keys.GET("", apiKeyHandler.GetAPIKeys)
+			keys.POST("", apiKeyHandler.CreateAPIKey)
+			keys.GET("/usage", apiKeyHandler.GetAPIKeysUsage)
+			keys.GET("/:id", apiKeyHandler.GetAPIKey)
+			keys.PUT("/:id", apiKeyHandler.UpdateAPIKey)
+			keys.DELETE("/:id", apiKeyHandler.DeleteAPIKey)
+			keys.PATCH("/:id", apiKeyHandler.ToggleAPIKey)
+			keys.GET("/:id/usage", apiKeyHandler.GetAPIKeyUsage)
+		}
+
+		prices := api.Group("/prices")
+		{
+			prices.GET("", priceHandler.GetPrices)
+			prices.POST("", priceHandler.CreatePrice)
+			prices.GET("/:id", priceHandler.GetPrice)
+			prices.PUT("/:id", priceHandler.UpdatePrice)
+			prices.DELETE("/:id", priceHandler.DeletePrice)
+		}
+
+		api.GET("/usage", usageHandler.GetUsage)
+
+		logs := api.Group("/logs")
+		{
+			logs.GET("", requestLogHandler.GetRequestLogs)
+			logs.GET("/:id", requestLogHandler.GetRequestLog)
+		}
+
 		// Debug routes
 		api.GET("/debug/models", proxyHandler.HandleDebugModels)
 		api.POST("/debug/models/reload", proxyHandler.HandleReloadModels)
@@ -55,11 +94,26 @@ func SetupWebRouter(indexHandler *IndexHandler, accountHandler *AccountHandler,
 }
 
 // SetupProxyRouter creates the proxy API router for /v1 routes
-func SetupProxyRouter(proxyHandler *ProxyHandler) *gin.Engine {
-	router := gin.Default()
+func SetupProxyRouter(proxyHandler *ProxyHandler, apiKeyDB *air_router_db.APIKeyDB, audit *services.AuditLogger, healthHandler *HealthHandler) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// Probes are registered ahead of the request middlewares so they are not logged, audited or measured
+	router.GET("/healthz", healthHandler.HandleHealthz)
+	router.GET("/readyz", healthHandler.HandleReadyz)
+	router.Use(RequestID(), AccessLog())
 
-	// Proxy routes - /v1/:path
-	router.Any("/v1/*path", proxyHandler.HandleProxy)
+	// Requests are measured before authentication so rejected ones are counted too
+	router.Use(RequestMetrics(services.Metrics))
+
+	// Proxy routes - /v1/:path, guarded by router-issued API keys
+	// Only authenticated requests reach the request log, so anonymous clients cannot fill the database
+	v1 := router.Group("/v1", APIKeyAuth(apiKeyDB), AuditLog(audit))
+	v1.Any("/*path", proxyHandler.HandleProxy)
+
+	// Gemini native routes - /v1beta/models/{model}:{method}
+	v1beta := router.Group("/v1beta", APIKeyAuth(apiKeyDB), AuditLog(audit))
+	v1beta.Any("/*path", proxyHandler.HandleGeminiProxy)
 
 	return router
 }
@@ -68,14 +122,33 @@ type Handlers struct {
 	IndexHandler   *IndexHandler
 	AccountHandler *AccountHandler
 	ModelHandler   *ModelHandler
+	APIKeyHandler  *APIKeyHandler
 	ProxyHandler   *ProxyHandler
+	PriceHandler   *PriceHandler
+	UsageHandler   *UsageHandler
+	LogHandler     *RequestLogHandler
+	HealthHandler  *HealthHandler
+	AuditLogger    *services.AuditLogger
 }
 
-func NewHandlers(frontendPath string, accountDB *air_router_db.AccountDB, modelDB *air_router_db.ModelDB) *Handlers {
+func NewHandlers(frontendPath string, accountDB *air_router_db.AccountDB, modelDB *air_router_db.ModelDB, apiKeyDB *air_router_db.APIKeyDB, affinityDB *air_router_db.ResourceAffinityDB, usageDB *air_router_db.UsageRecordDB, priceDB *air_router_db.PriceDB, requestLogDB *air_router_db.RequestLogDB) *Handlers {
+	// The rate limiter is shared so the admin API sees the proxy's counters
+	rateLimiter := services.NewRateLimiter(apiKeyDB)
+
+	// Budget pauses live in memory and are restored from the usage records
+	budgets := services.NewBudgetGuard(usageDB)
+	go budgets.EvaluateAll(accountDB)
+
 	return &Handlers{
 		IndexHandler:   NewIndexHandler(frontendPath),
-		AccountHandler: NewAccountHandler(accountDB, modelDB),
+		AccountHandler: NewAccountHandler(accountDB, modelDB, budgets),
 		ModelHandler:   NewModelHandler(modelDB),
-		ProxyHandler:   NewProxyHandler(accountDB, modelDB),
+		APIKeyHandler:  NewAPIKeyHandler(apiKeyDB, rateLimiter),
+		ProxyHandler:   NewProxyHandler(accountDB, modelDB, rateLimiter, services.NewResourceAffinity(affinityDB), usageDB, services.NewPricing(priceDB), budgets),
+		PriceHandler:   NewPriceHandler(priceDB),
+		UsageHandler:   NewUsageHandler(usageDB),
+		LogHandler:     NewRequestLogHandler(requestLogDB),
+		HealthHandler:  NewHealthHandler(accountDB.DB),
+		AuditLogger:    services.NewAuditLogger(requestLogDB),
 	}
 }
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultUsageDays is the range summarized when the request gives no from date
+const defaultUsageDays = 30
+
+type UsageHandler struct {
+	UsageDB *db.UsageRecordDB
+}
+
+func NewUsageHandler(usageDB *db.UsageRecordDB) *UsageHandler {
+	return &UsageHandler{
+		UsageDB: usageDB,
+	}
+}
+
+// GetUsage handles GET /api/usage?group_by=day,account&from=2006-01-02&to=2006-01-02
+// Aggregates requests, tokens and spend over UTC days, to being inclusive
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	groupBy := []string{models.UsageGroupDay}
+	if value := c.Query("group_by"); value != "" {
+		groupBy = nil
+		for _, group := range strings.Split(value, ",") {
+			group = strings.TrimSpace(group)
+			if !common.ValidateUsageGroup(group) {
+				common.SendAPIError(c, http.StatusBadRequest, fmt.Sprintf(common.ErrMsgInvalidUsageGroup, group), common.ErrTypeInvalidRequest)
+				return
+			}
+			groupBy = append(groupBy, group)
+		}
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, fromErr := parseUsageDate(c.Query("from"), today.AddDate(0, 0, 1-defaultUsageDays))
+	to, toErr := parseUsageDate(c.Query("to"), today)
+	if fromErr != nil || toErr != nil || to.Before(from) {
+		common.SendAPIError(c, http.StatusBadRequest, common.ErrMsgInvalidDateRange, common.ErrTypeInvalidRequest)
+		return
+	}
+	to = to.AddDate(0, 0, 1)
+
+	summaries, err := h.UsageDB.GetUsageSummary(groupBy, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		common.SendAPIError(c, http.StatusInternalServerError, err.Error(), common.ErrTypeInternalServer)
+		return
+	}
+
+	total := models.UsageSummary{}
+	for _, summary := range summaries {
+		total.Requests += summary.Requests
+		total.PromptTokens += summary.PromptTokens
+		total.CompletionTokens += summary.CompletionTokens
+		total.CachedTokens += summary.CachedTokens
+		total.Cost += summary.Cost
+	}
+
+	common.SendJSONResponse(c, http.StatusOK, gin.H{
+		"data":     summaries,
+		"total":    total,
+		"group_by": groupBy,
+		"from":     from.Format(time.DateOnly),
+		"to":       to.AddDate(0, 0, -1).Format(time.DateOnly),
+	})
+}
+
+// parseUsageDate parses a YYYY-MM-DD date as UTC midnight
+func parseUsageDate(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
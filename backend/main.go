@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
+	"air_router/cache"
 	air_router_db "air_router/db"
 	air_router_handlers "air_router/handlers"
+	"air_router/utils/common"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,6 +34,9 @@ func main() {
 	// Seed the random number generator
 	rand.Seed(time.Now().UnixNano())
 
+	// Install the structured logger configured by LOG_LEVEL and LOG_FORMAT
+	common.InitLogger()
+
 	// Set gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -37,7 +49,7 @@ func main() {
 
 	// Initialize configuration directory
 	if err := initConfigDir(*configDir); err != nil {
-		log.Fatal("Error creating config directory: ", err)
+		fatal("error creating config directory", err)
 	}
 
 	// Setup database path
@@ -46,15 +58,14 @@ func main() {
 	// Convert frontend path to absolute path
 	absFrontendPath, err := filepath.Abs(*frontendPath)
 	if err != nil {
-		log.Fatal("Error resolving frontend path: ", err)
+		fatal("error resolving frontend path", err)
 	}
 
 	// Initialize database
 	dbConn, err := air_router_db.InitDB(dbPath)
 	if err != nil {
-		log.Fatal("Error initializing database: ", err)
+		fatal("error initializing database", err)
 	}
-	defer dbConn.Close()
 
 	// Initialize account database handler
 	accountDB := &air_router_db.AccountDB{DB: dbConn}
@@ -62,27 +73,105 @@ func main() {
 	// Initialize model database handler
 	modelDB := &air_router_db.ModelDB{DB: dbConn}
 
+	// Initialize api key database handler
+	apiKeyDB := &air_router_db.APIKeyDB{DB: dbConn}
+
+	// Initialize resource affinity database handler
+	affinityDB := &air_router_db.ResourceAffinityDB{DB: dbConn}
+
+	// Initialize usage record database handler
+	usageDB := &air_router_db.UsageRecordDB{DB: dbConn}
+
+	// Initialize price catalog database handler
+	priceDB := &air_router_db.PriceDB{DB: dbConn}
+
+	// Initialize request log database handler
+	requestLogDB := &air_router_db.RequestLogDB{DB: dbConn}
+
 	// Initialize handlers
-	handlers := air_router_handlers.NewHandlers(absFrontendPath, accountDB, modelDB)
+	handlers := air_router_handlers.NewHandlers(absFrontendPath, accountDB, modelDB, apiKeyDB, affinityDB, usageDB, priceDB, requestLogDB)
 
 	// Setup routers
-	webRouter := air_router_handlers.SetupWebRouter(handlers.IndexHandler, handlers.AccountHandler, handlers.ModelHandler, handlers.ProxyHandler, absFrontendPath)
-	proxyRouter := air_router_handlers.SetupProxyRouter(handlers.ProxyHandler)
+	webRouter := air_router_handlers.SetupWebRouter(handlers.IndexHandler, handlers.AccountHandler, handlers.ModelHandler, handlers.APIKeyHandler, handlers.ProxyHandler, handlers.PriceHandler, handlers.UsageHandler, handlers.LogHandler, handlers.HealthHandler, absFrontendPath)
+	proxyRouter := air_router_handlers.SetupProxyRouter(handlers.ProxyHandler, apiKeyDB, handlers.AuditLogger, handlers.HealthHandler)
 
-	// Start web server
-	webAddr := ":" + *webPort
+	// Stop on SIGTERM or Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Start the background task to refresh models cache
+	cacheDone := make(chan struct{})
 	go func() {
-		log.Printf("Web server starting on http://localhost%s", webAddr)
-		if err := webRouter.Run(webAddr); err != nil {
-			log.Fatal("Web server error: ", err)
-		}
+		defer close(cacheDone)
+		cache.StartModelsCacheTask(ctx, accountDB, modelDB)
 	}()
 
-	// Start proxy server
+	// Start web and proxy servers
+	webAddr := ":" + *webPort
 	proxyAddr := ":" + *port
+	servers := map[string]*http.Server{
+		"web":   {Addr: webAddr, Handler: webRouter},
+		"proxy": {Addr: proxyAddr, Handler: proxyRouter},
+	}
+	serverErrors := make(chan error, len(servers))
+	for name, server := range servers {
+		go func() {
+			slog.Info(name+" server starting", "addr", server.Addr)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErrors <- fmt.Errorf("%s server: %w", name, err)
+			}
+		}()
+	}
 	printStartupInfo(webAddr, proxyAddr, absFrontendPath, dbPath)
-	if err := proxyRouter.Run(proxyAddr); err != nil {
-		log.Fatal("Proxy server error: ", err)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErrors:
+		fatal("server error", err)
+	}
+	stop()
+
+	shutdown(handlers.HealthHandler, servers, cacheDone)
+	if err := dbConn.Close(); err != nil {
+		slog.Error("error closing database", "error", err)
+	}
+	slog.Info("AI Router Server stopped")
+}
+
+// shutdown fails /readyz and keeps serving for SHUTDOWN_DRAIN_DELAY_SECONDS so load balancers stop sending traffic,
+// then stops accepting new requests and waits for in-flight ones, streams included,
+// and for the models cache task, up to SHUTDOWN_TIMEOUT_SECONDS; connections still open then are closed
+func shutdown(health *air_router_handlers.HealthHandler, servers map[string]*http.Server, cacheDone <-chan struct{}) {
+	drainDelay := time.Duration(common.GetEnvIntOrDefault("SHUTDOWN_DRAIN_DELAY_SECONDS", 5)) * time.Second
+	timeout := time.Duration(common.GetEnvIntOrDefault("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second
+
+	health.SetShuttingDown()
+	if drainDelay > 0 {
+		slog.Info("shutting down, reporting not ready before closing listeners", "delay", drainDelay.String())
+		time.Sleep(drainDelay)
+	}
+	slog.Info("shutting down, draining in-flight requests", "timeout", timeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for name, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Warn(name+" server did not drain in time, closing remaining connections", "error", err)
+				server.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-cacheDone:
+	case <-ctx.Done():
+		slog.Warn("models cache task did not stop in time")
 	}
 }
 
@@ -94,21 +183,23 @@ func initConfigDir(dir string) error {
 	return nil
 }
 
-// printStartupInfo prints server startup information
+// printStartupInfo logs server startup information
 func printStartupInfo(webAddr, proxyAddr, frontendPath, dbPath string) {
-	log.Println("================================")
-	log.Println("  AI Router Server")
-	log.Println("================================")
-	log.Printf("  Version:    %s", Version)
-	log.Printf("  Build:      %s", BuildTime)
-	log.Printf("  Git Commit: %s", GitCommit)
-	log.Println("--------------------------------")
-	log.Printf("  Web Interface:    http://127.0.0.1%s", webAddr)
-	log.Println("  Web endpoints:     /, /debug, /api/*")
-	log.Printf("  Frontend:   %s", frontendPath)
-	log.Printf("  Database:   %s", dbPath)
-	log.Println("--------------------------------")
-	log.Printf("  Proxy API:        http://127.0.0.1%s", proxyAddr)
-	log.Println("  Proxy endpoints:   /v1/*")
-	log.Println("================================")
+	slog.Info("AI Router Server starting",
+		"version", Version,
+		"build", BuildTime,
+		"git_commit", GitCommit,
+		"web_interface", "http://127.0.0.1"+webAddr,
+		"web_endpoints", "/, /debug, /metrics, /healthz, /readyz, /api/*",
+		"frontend", frontendPath,
+		"database", dbPath,
+		"proxy_api", "http://127.0.0.1"+proxyAddr,
+		"proxy_endpoints", "/v1/*, /v1beta/* (Gemini), /healthz, /readyz",
+	)
+}
+
+// fatal logs an error that prevents the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
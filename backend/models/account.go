@@ -1,5 +1,26 @@
 package models
 
+// AccountProtocol is the API protocol an account speaks natively
+type AccountProtocol string
+
+const (
+	ProtocolOpenAI    AccountProtocol = "openai"    // OpenAI compatible API, Bearer auth
+	ProtocolAnthropic AccountProtocol = "anthropic" // Native Anthropic API, X-Api-Key auth
+	ProtocolGemini    AccountProtocol = "gemini"    // Native Gemini API, x-goog-api-key auth
+
+	DefaultAccountProtocol = ProtocolOpenAI
+)
+
+// BudgetPeriod is the window over which an account's spend is compared to its budget
+type BudgetPeriod string
+
+const (
+	BudgetPeriodMonthly BudgetPeriod = "monthly" // Calendar month in UTC, the account resumes on the 1st
+	BudgetPeriodTotal   BudgetPeriod = "total"   // All recorded spend, the account resumes once the budget is raised
+
+	DefaultBudgetPeriod = BudgetPeriodMonthly
+)
+
 // Account represents an account entity
 type Account struct {
 	ID              int    `json:"id"`
@@ -8,6 +29,22 @@ type Account struct {
 	APIKey          string `json:"api_key"`
 	Enabled         bool   `json:"enabled"`
 	ClaudeAvailable bool   `json:"claude_available"`
-	Ext             string `json:"ext,omitempty"`
-	UpdatedAt       int64  `json:"updated_at"`
+	// Serves the OpenAI Responses API natively, otherwise codex aliases translate to Chat Completions
+	ResponsesAvailable bool            `json:"responses_available"`
+	Protocol           AccountProtocol `json:"protocol"`
+	Ext                string          `json:"ext,omitempty"`
+	Weight             int             `json:"weight"`   // Relative share of traffic within a priority tier
+	Priority           int             `json:"priority"` // Higher tiers are tried first
+	// Applied to catalog prices, e.g. 0.8 for a key resold at a 20% discount
+	CostMultiplier float64 `json:"cost_multiplier"`
+	// Spend limit in USD over BudgetPeriod, 0 means unlimited
+	Budget       float64      `json:"budget"`
+	BudgetPeriod BudgetPeriod `json:"budget_period"`
+	// Why the account is excluded from routing while enabled, e.g. a reached budget; not stored
+	PausedReason string `json:"paused_reason,omitempty"`
+	// Upstream timeouts in milliseconds, 0 uses the global default
+	ConnectTimeoutMs   int   `json:"connect_timeout_ms"`
+	FirstByteTimeoutMs int   `json:"first_byte_timeout_ms"`
+	IdleTimeoutMs      int   `json:"idle_timeout_ms"`
+	UpdatedAt          int64 `json:"updated_at"`
 }
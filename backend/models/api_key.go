@@ -0,0 +1,43 @@
+package models
+
+// APIKey represents a client API key issued by the router for the /v1 proxy
+// Limits of 0 mean unlimited
+type APIKey struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Key               string `json:"key"`
+	Enabled           bool   `json:"enabled"`
+	Ext               string `json:"ext,omitempty"`
+	RPMLimit          int64  `json:"rpm_limit"`
+	TokensPerDayLimit int64  `json:"tokens_per_day_limit"`
+	MonthlyTokenLimit int64  `json:"monthly_token_limit"`
+	UpdatedAt         int64  `json:"updated_at"`
+}
+
+// Usage period names for api key counters
+const (
+	UsagePeriodMinute = "minute"
+	UsagePeriodDay    = "day"
+	UsagePeriodMonth  = "month"
+)
+
+// UsageCounter holds the request and token counts of one usage period
+// HedgedRequests counts the extra upstream requests sent by hedging, which are not part of Requests
+type UsageCounter struct {
+	Requests       int64 `json:"requests"`
+	Tokens         int64 `json:"tokens"`
+	HedgedRequests int64 `json:"hedged_requests"`
+}
+
+// APIKeyUsage represents the current consumption of an api key against its limits
+type APIKeyUsage struct {
+	APIKeyID          int          `json:"api_key_id"`
+	Name              string       `json:"name"`
+	Enabled           bool         `json:"enabled"`
+	Minute            UsageCounter `json:"minute"`
+	Day               UsageCounter `json:"day"`
+	Month             UsageCounter `json:"month"`
+	RPMLimit          int64        `json:"rpm_limit"`
+	TokensPerDayLimit int64        `json:"tokens_per_day_limit"`
+	MonthlyTokenLimit int64        `json:"monthly_token_limit"`
+}
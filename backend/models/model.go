@@ -10,12 +10,31 @@ const (
 	ProviderGemini Provider = "gemini"
 )
 
+// RoutingStrategy represents how an alias model picks its upstream model and account
+type RoutingStrategy string
+
+const (
+	StrategyRandom        RoutingStrategy = "random"
+	StrategyRoundRobin    RoutingStrategy = "round_robin"
+	StrategyWeighted      RoutingStrategy = "weighted"
+	StrategyFailover      RoutingStrategy = "failover"
+	StrategyLeastLatency  RoutingStrategy = "least_latency"
+	StrategyLeastInFlight RoutingStrategy = "least_inflight"
+
+	DefaultRoutingStrategy = StrategyWeighted
+)
+
 // Model represents a model entity
 type Model struct {
-	ID          int      `json:"id"`
-	ModelID     string   `json:"model_id"`
-	AssModelIDs []string `json:"ass_model_ids,omitempty"` // Associated model IDs
-	Provider    Provider `json:"provider"`
-	Enabled     bool     `json:"enabled"`
-	UpdatedAt   int64    `json:"updated_at"`
+	ID          int             `json:"id"`
+	ModelID     string          `json:"model_id"`
+	AssModelIDs []string        `json:"ass_model_ids,omitempty"` // Associated model IDs
+	Provider    Provider        `json:"provider"`
+	Strategy    RoutingStrategy `json:"strategy"`
+	// Send the request to a second account when the first has not answered after this many milliseconds, 0 disables hedging
+	HedgeDelayMs int `json:"hedge_delay_ms"`
+	// Report the alias instead of the upstream model in the model field of responses and stream events
+	RewriteModel bool  `json:"rewrite_model"`
+	Enabled      bool  `json:"enabled"`
+	UpdatedAt    int64 `json:"updated_at"`
 }
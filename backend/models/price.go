@@ -0,0 +1,24 @@
+package models
+
+// ModelPrice is the catalog price of an upstream model in USD per million tokens
+// ModelID may end with * to price every model sharing the prefix; the longest match wins
+type ModelPrice struct {
+	ID               int     `json:"id"`
+	ModelID          string  `json:"model_id"`
+	InputPrice       float64 `json:"input_price"`
+	OutputPrice      float64 `json:"output_price"`
+	CachedInputPrice float64 `json:"cached_input_price"` // 0 bills cached tokens at InputPrice
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
+// Cost returns the price in USD of a request's tokens, cachedTokens being part of promptTokens
+func (p ModelPrice) Cost(promptTokens, completionTokens, cachedTokens int64) float64 {
+	cachedPrice := p.CachedInputPrice
+	if cachedPrice == 0 {
+		cachedPrice = p.InputPrice
+	}
+	cost := float64(promptTokens-cachedTokens)*p.InputPrice +
+		float64(cachedTokens)*cachedPrice +
+		float64(completionTokens)*p.OutputPrice
+	return cost / 1e6
+}
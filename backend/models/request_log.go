@@ -0,0 +1,47 @@
+package models
+
+// RequestLog is the audit row written for a proxied request when request logging is enabled
+// Bodies are only stored when body logging is opted into, size-capped and with secrets redacted
+type RequestLog struct {
+	ID               int64            `json:"id"`
+	CreatedAt        int64            `json:"created_at"`
+	RequestID        string           `json:"request_id"`
+	Method           string           `json:"method"`
+	Path             string           `json:"path"`
+	Query            string           `json:"query"` // with key-like parameters redacted
+	ClientIP         string           `json:"client_ip"`
+	UserAgent        string           `json:"user_agent"`
+	APIKeyID         int              `json:"api_key_id"` // 0 when unauthenticated or auth is disabled
+	Alias            string           `json:"alias"`
+	UpstreamModel    string           `json:"upstream_model"`
+	AccountID        int              `json:"account_id"` // last account attempted, 0 when none was
+	AccountName      string           `json:"account_name"`
+	Attempts         []RequestAttempt `json:"attempts"`
+	Status           int              `json:"status"`
+	LatencyMs        int64            `json:"latency_ms"`
+	PromptTokens     int64            `json:"prompt_tokens"`
+	CompletionTokens int64            `json:"completion_tokens"`
+	RequestBody      string           `json:"request_body,omitempty"`
+	ResponseBody     string           `json:"response_body,omitempty"`
+}
+
+// RequestAttempt describes one upstream attempt made for a request
+type RequestAttempt struct {
+	AccountID     int    `json:"account_id"`
+	AccountName   string `json:"account_name"`
+	UpstreamModel string `json:"upstream_model"`
+	Status        int    `json:"status"`            // 0 when the account gave no response
+	Failure       string `json:"failure,omitempty"` // retry policy class, empty on success
+}
+
+// RequestLogFilter narrows down the request logs listed by GET /api/logs
+// Zero values do not filter, timestamps are in milliseconds and To is exclusive
+type RequestLogFilter struct {
+	RequestID string
+	Status    int
+	AccountID int
+	APIKeyID  int
+	Alias     string
+	From      int64
+	To        int64
+}
@@ -0,0 +1,45 @@
+package models
+
+// UsageRecord is the accounting row written for every proxied request
+// AccountID, Alias and UpstreamModel describe the last account attempted and are empty when none was
+type UsageRecord struct {
+	ID               int64   `json:"id"`
+	CreatedAt        int64   `json:"created_at"`
+	APIKeyID         int     `json:"api_key_id"` // 0 when client key auth is disabled
+	AccountID        int     `json:"account_id"`
+	Alias            string  `json:"alias"` // empty when routed by upstream model ID
+	UpstreamModel    string  `json:"upstream_model"`
+	Path             string  `json:"path"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CachedTokens     int64   `json:"cached_tokens"`
+	Cost             float64 `json:"cost"` // USD
+	LatencyMs        int64   `json:"latency_ms"`
+	Status           int     `json:"status"`
+}
+
+// Usage summary dimensions accepted by GET /api/usage
+const (
+	UsageGroupDay     = "day"
+	UsageGroupAccount = "account"
+	UsageGroupAlias   = "alias"
+	UsageGroupAPIKey  = "api_key"
+	UsageGroupModel   = "model"
+)
+
+// UsageSummary aggregates the usage records of one group
+// Only the fields of the requested dimensions are set
+type UsageSummary struct {
+	Day              string  `json:"day,omitempty"`
+	AccountID        *int    `json:"account_id,omitempty"`
+	AccountName      *string `json:"account_name,omitempty"`
+	Alias            *string `json:"alias,omitempty"`
+	APIKeyID         *int    `json:"api_key_id,omitempty"`
+	APIKeyName       *string `json:"api_key_name,omitempty"`
+	UpstreamModel    *string `json:"upstream_model,omitempty"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CachedTokens     int64   `json:"cached_tokens"`
+	Cost             float64 `json:"cost"`
+}
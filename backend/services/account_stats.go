@@ -0,0 +1,88 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyEWMAAlpha is the weight of the newest sample in the latency moving average
+const latencyEWMAAlpha = 0.3
+
+// accountStats holds live traffic statistics of one account
+type accountStats struct {
+	inFlight int64
+	latency  time.Duration // Exponentially weighted moving average of time to response headers
+	samples  int64
+}
+
+// AccountStatsTracker tracks in-flight requests and response latency per account
+type AccountStatsTracker struct {
+	mu    sync.RWMutex
+	stats map[int]*accountStats
+}
+
+// AccountStats is the global account statistics tracker
+var AccountStats = &AccountStatsTracker{
+	stats: make(map[int]*accountStats),
+}
+
+// get returns the stats entry of an account, creating it if needed
+// Must be called with t.mu held for writing
+func (t *AccountStatsTracker) get(accountID int) *accountStats {
+	stats, exists := t.stats[accountID]
+	if !exists {
+		stats = &accountStats{}
+		t.stats[accountID] = stats
+	}
+	return stats
+}
+
+// Begin marks the start of a request to an account
+func (t *AccountStatsTracker) Begin(accountID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(accountID).inFlight++
+}
+
+// End marks the end of a request to an account
+func (t *AccountStatsTracker) End(accountID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.get(accountID)
+	if stats.inFlight > 0 {
+		stats.inFlight--
+	}
+}
+
+// ObserveLatency records the time an account took to return response headers
+func (t *AccountStatsTracker) ObserveLatency(accountID int, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.get(accountID)
+	if stats.samples == 0 {
+		stats.latency = latency
+	} else {
+		stats.latency = time.Duration(latencyEWMAAlpha*float64(latency) + (1-latencyEWMAAlpha)*float64(stats.latency))
+	}
+	stats.samples++
+}
+
+// InFlight returns the number of requests currently running against an account
+func (t *AccountStatsTracker) InFlight(accountID int) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if stats, exists := t.stats[accountID]; exists {
+		return stats.inFlight
+	}
+	return 0
+}
+
+// Latency returns the average latency of an account and whether it has been measured
+func (t *AccountStatsTracker) Latency(accountID int) (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if stats, exists := t.stats[accountID]; exists && stats.samples > 0 {
+		return stats.latency, true
+	}
+	return 0, false
+}
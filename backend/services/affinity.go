@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils/common"
+)
+
+// resourcePrefixes maps the paths of objects stored upstream to their kind, longest prefix first
+var resourcePrefixes = []struct {
+	prefix string
+	kind   string
+}{
+	{"/messages/batches", "batch"},
+	{"/fine_tuning/jobs", "fine_tuning_job"},
+	{"/vector_stores", "vector_store"},
+	{"/batches", "batch"},
+	{"/files", "file"},
+	{"/uploads", "upload"},
+	{"/responses", "response"},
+}
+
+// resourceReferencePattern matches IDs of stored objects referenced from a request body
+var resourceReferencePattern = regexp.MustCompile(`"(?:file_id|input_file_id|previous_response_id|batch_id|vector_store_id)"\s*:\s*"([^"]+)"`)
+
+// maxCapturedResponseBytes caps how much of a creating response is kept to read the new object's ID
+const maxCapturedResponseBytes = 1 << 20
+
+// ResourceAffinity remembers which account created a stored object (file, batch, response...)
+// so that follow-up requests referencing its ID reach the same account
+type ResourceAffinity struct {
+	AffinityDB *db.ResourceAffinityDB
+	TTL        time.Duration // 0 keeps records forever
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+// NewResourceAffinity creates a new ResourceAffinity
+func NewResourceAffinity(affinityDB *db.ResourceAffinityDB) *ResourceAffinity {
+	return &ResourceAffinity{
+		AffinityDB: affinityDB,
+		TTL:        time.Duration(common.GetEnvIntOrDefault("RESOURCE_AFFINITY_TTL_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+// resourceOf returns the kind of object a path addresses and the object ID it names, if any
+func resourceOf(path string) (kind string, id string, ok bool) {
+	path, _, _ = strings.Cut(path, "?")
+	for _, resource := range resourcePrefixes {
+		rest, found := strings.CutPrefix(path, resource.prefix)
+		if !found || (rest != "" && rest[0] != '/') {
+			continue
+		}
+		id, _, _ = strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+		return resource.kind, id, true
+	}
+	return "", "", false
+}
+
+// IsResourcePath reports whether a path addresses objects stored upstream, such as /files or /batches/{id}
+func IsResourcePath(path string) bool {
+	_, _, ok := resourceOf(path)
+	return ok
+}
+
+// Owner returns the account that created an object referenced by the path or the body
+func (a *ResourceAffinity) Owner(ctx context.Context, path string, body []byte) (int, bool) {
+	if a == nil || a.AffinityDB == nil {
+		return 0, false
+	}
+
+	var ids []string
+	if _, id, ok := resourceOf(path); ok && id != "" {
+		ids = append(ids, id)
+	}
+	for _, match := range resourceReferencePattern.FindAllSubmatch(body, -1) {
+		ids = append(ids, string(match[1]))
+	}
+
+	for _, id := range ids {
+		accountID, err := a.AffinityDB.GetResourceAccount(id)
+		if err == nil {
+			return accountID, true
+		}
+		if err != sql.ErrNoRows {
+			slog.ErrorContext(ctx, "error looking up resource", "component", "affinity", "resource", id, "error", err)
+		}
+	}
+	return 0, false
+}
+
+// Pin narrows accounts, those whose protocol can serve the request, down to the owner of a referenced object
+// When the owner is not one of them the accounts are returned unchanged; pinned reports whether the owner was kept
+func (a *ResourceAffinity) Pin(ctx context.Context, accounts []models.Account, path string, body []byte) (result []models.Account, pinned bool) {
+	accountID, ok := a.Owner(ctx, path, body)
+	if !ok {
+		return accounts, false
+	}
+	for _, account := range accounts {
+		if account.ID == accountID {
+			slog.InfoContext(ctx, "request pinned to resource owner", "component", "affinity", "path", path, "account", account.Name, "account_id", account.ID)
+			return []models.Account{account}, true
+		}
+	}
+	slog.InfoContext(ctx, "resource owner cannot serve this request, routing normally", "component", "affinity", "path", path, "account_id", accountID)
+	return accounts, false
+}
+
+// Track records the objects created by a successful response, and forgets deleted ones
+// It must be called before the response body is relayed, since the created IDs are read while it streams
+func (a *ResourceAffinity) Track(ctx context.Context, account models.Account, method, path string, resp *http.Response) {
+	if a == nil || a.AffinityDB == nil || resp.StatusCode >= http.StatusBadRequest {
+		return
+	}
+	kind, id, ok := resourceOf(path)
+	if !ok {
+		return
+	}
+
+	switch method {
+	case http.MethodDelete:
+		if id != "" {
+			if err := a.AffinityDB.DeleteResource(id); err != nil {
+				slog.ErrorContext(ctx, "error forgetting resource", "component", "affinity", "resource", id, "error", err)
+			}
+		}
+	case http.MethodPost:
+		resp.Body = &responseCapture{
+			ReadCloser: resp.Body,
+			onClose: func(body []byte) {
+				for _, createdID := range createdResourceIDs(body) {
+					a.record(ctx, createdID, account, kind)
+				}
+			},
+		}
+	}
+}
+
+// record stores the owner of a created object and prunes expired records at most once an hour
+func (a *ResourceAffinity) record(ctx context.Context, resourceID string, account models.Account, kind string) {
+	if err := a.AffinityDB.SetResourceAccount(resourceID, account.ID, kind); err != nil {
+		slog.ErrorContext(ctx, "error recording resource", "component", "affinity", "resource", resourceID, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "resource created", "component", "affinity", "resource", resourceID, "kind", kind, "account", account.Name, "account_id", account.ID)
+
+	if a.TTL <= 0 {
+		return
+	}
+	a.mu.Lock()
+	due := time.Since(a.lastPruned) >= time.Hour
+	if due {
+		a.lastPruned = time.Now()
+	}
+	a.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := a.AffinityDB.DeleteResourcesBefore(time.Now().Add(-a.TTL).UnixMilli()); err != nil {
+		slog.ErrorContext(ctx, "error pruning resources", "component", "affinity", "error", err)
+	}
+}
+
+// createdResourceIDs returns the IDs announced by a creating response, a JSON object or an SSE stream
+func createdResourceIDs(body []byte) []string {
+	payload := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(payload, []byte("{")) {
+		// Streams announce the object in their first data event
+		payload = nil
+		for _, line := range bytes.Split(bytes.TrimSpace(body), []byte("\n")) {
+			if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+				payload = bytes.TrimSpace(data)
+				break
+			}
+		}
+	}
+
+	var object struct {
+		ID       string `json:"id"`
+		Response struct {
+			ID string `json:"id"`
+		} `json:"response"`
+		File struct {
+			ID string `json:"id"`
+		} `json:"file"` // completed uploads carry the resulting file
+	}
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil
+	}
+
+	var ids []string
+	for _, id := range []string{object.ID, object.Response.ID, object.File.ID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// responseCapture keeps the beginning of a response body while it is relayed and hands it over once closed
+type responseCapture struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	onClose func([]byte)
+	closed  bool
+}
+
+func (r *responseCapture) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if room := maxCapturedResponseBytes - r.buf.Len(); room > 0 {
+		r.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+func (r *responseCapture) Close() error {
+	if !r.closed {
+		r.closed = true
+		r.onClose(r.buf.Bytes())
+	}
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"air_router/constants"
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedValue replaces secrets found in logged bodies and query strings
+const redactedValue = "[REDACTED]"
+
+// secretFieldPattern matches JSON string fields whose name suggests a credential
+var secretFieldPattern = regexp.MustCompile(`(?i)("[^"]*(?:api[_-]?key|secret|password|token|authorization|credential)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// secretValuePattern matches well-known credential formats wherever they appear
+var secretValuePattern = regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9._~+/=-]+|\bsk-[A-Za-z0-9_-]{8,}|\bAIza[0-9A-Za-z_-]{20,}`)
+
+// AuditLogger persists the metadata of proxied requests, and optionally their bodies, into request_logs
+type AuditLogger struct {
+	RequestLogDB *db.RequestLogDB
+	Enabled      bool
+	Bodies       bool          // also store request and response bodies
+	MaxBodyBytes int           // bytes kept of each body
+	Retention    time.Duration // 0 keeps logs forever
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+// NewAuditLogger creates an AuditLogger configured from the AUDIT_LOG_* environment variables
+func NewAuditLogger(requestLogDB *db.RequestLogDB) *AuditLogger {
+	return &AuditLogger{
+		RequestLogDB: requestLogDB,
+		Enabled:      common.GetEnvOrDefault("AUDIT_LOG_ENABLED", "false") == "true",
+		Bodies:       common.GetEnvOrDefault("AUDIT_LOG_BODIES", "false") == "true",
+		MaxBodyBytes: max(common.GetEnvIntOrDefault("AUDIT_LOG_MAX_BODY_BYTES", 16384), 0),
+		Retention:    time.Duration(common.GetEnvIntOrDefault("AUDIT_LOG_RETENTION_DAYS", 7)) * 24 * time.Hour,
+	}
+}
+
+// Record stores a request log and prunes logs past the retention at most once an hour
+func (a *AuditLogger) Record(entry models.RequestLog) {
+	if a == nil || !a.Enabled || a.RequestLogDB == nil {
+		return
+	}
+	if err := a.RequestLogDB.CreateRequestLog(entry); err != nil {
+		slog.Error("error recording request log", "component", "audit", "error", err)
+		return
+	}
+
+	if a.Retention <= 0 {
+		return
+	}
+	a.mu.Lock()
+	due := time.Since(a.lastPruned) >= time.Hour
+	if due {
+		a.lastPruned = time.Now()
+	}
+	a.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := a.RequestLogDB.DeleteRequestLogsBefore(time.Now().Add(-a.Retention).UnixMilli()); err != nil {
+		slog.Error("error pruning request logs", "component", "audit", "error", err)
+	}
+}
+
+// LoggableBody renders a captured body for storage: textual bodies are redacted and
+// marked when truncated, other content types are summarized by type and size
+func (a *AuditLogger) LoggableBody(contentType string, captured []byte, size int64) string {
+	if size == 0 {
+		return ""
+	}
+	if !isTextualContentType(contentType) {
+		return "[" + contentType + " body omitted]"
+	}
+	body := RedactSecrets(string(captured))
+	if size > int64(len(captured)) {
+		body += "...[truncated]"
+	}
+	return body
+}
+
+// isTextualContentType reports whether a body of this content type is worth storing as text
+func isTextualContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if contentType == "" || strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	for _, textual := range []string{"json", "x-www-form-urlencoded", "xml"} {
+		if strings.Contains(contentType, textual) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecrets masks credential-like JSON fields and well-known key formats in a body
+func RedactSecrets(body string) string {
+	body = secretFieldPattern.ReplaceAllString(body, `$1"`+redactedValue+`"`)
+	return secretValuePattern.ReplaceAllString(body, redactedValue)
+}
+
+// RedactQuery masks the values of key-like query parameters, such as Gemini's ?key=
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redactedValue
+	}
+	for name := range values {
+		lower := strings.ToLower(name)
+		if lower == "key" || strings.Contains(lower, "token") || strings.Contains(lower, "secret") {
+			values[name] = []string{redactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+// NoteAttempt makes an account the one a request is accounted to and appends the attempt to its audit trail
+func NoteAttempt(c *gin.Context, account models.Account, modelID string, resp *http.Response, failure FailureClass) {
+	c.Set(constants.ContextKeyAccount, account)
+	c.Set(constants.ContextKeyUpstreamModel, modelID)
+
+	attempt := models.RequestAttempt{
+		AccountID:     account.ID,
+		AccountName:   account.Name,
+		UpstreamModel: modelID,
+		Failure:       string(failure),
+	}
+	if resp != nil {
+		attempt.Status = resp.StatusCode
+	}
+	attempts, _ := c.Get(constants.ContextKeyAttempts)
+	list, _ := attempts.([]models.RequestAttempt)
+	c.Set(constants.ContextKeyAttempts, append(list, attempt))
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"air_router/models"
+	"air_router/utils/common"
+)
+
+// Balancer picks the upstream model and account that serve an alias model
+type Balancer interface {
+	// SelectModel picks one of the associated model IDs of an alias
+	SelectModel(alias string, modelIDs []string) string
+	// SelectAccount picks one of the candidate accounts, which is never empty
+	SelectAccount(alias string, accounts []models.Account) models.Account
+}
+
+// balancers holds the shared instance of every strategy
+var balancers = map[models.RoutingStrategy]Balancer{
+	models.StrategyRandom:        &randomBalancer{},
+	models.StrategyRoundRobin:    &roundRobinBalancer{},
+	models.StrategyWeighted:      &weightedBalancer{},
+	models.StrategyFailover:      &failoverBalancer{},
+	models.StrategyLeastLatency:  &leastLatencyBalancer{},
+	models.StrategyLeastInFlight: &leastInFlightBalancer{},
+}
+
+// GetBalancer returns the balancer of a routing strategy, falling back to the default strategy
+func GetBalancer(strategy models.RoutingStrategy) Balancer {
+	if balancer, exists := balancers[strategy]; exists {
+		return balancer
+	}
+	return balancers[models.DefaultRoutingStrategy]
+}
+
+// fallbackBalancer is implemented by strategies that move on to the next associated model
+// once every account of the previous one failed or is unavailable
+type fallbackBalancer interface {
+	// OrderModels returns the associated model IDs in the order they are tried
+	OrderModels(alias string, modelIDs []string) []string
+}
+
+// CandidateModels returns the upstream models tried for an alias, in order
+// Strategies without model fallback try the single model they select
+func CandidateModels(balancer Balancer, alias string, modelIDs []string) []string {
+	if fallback, ok := balancer.(fallbackBalancer); ok {
+		return fallback.OrderModels(alias, modelIDs)
+	}
+	return []string{balancer.SelectModel(alias, modelIDs)}
+}
+
+// SelectAccount picks an untried account for an upstream model within the highest priority tier using the balancer
+// Accounts whose circuit for the model is open are skipped; a tier is only left once all of its accounts were tried or are unavailable
+// The returned account already holds a circuit breaker slot; ok is false when no account is left
+func SelectAccount(accounts []models.Account, tried map[int]bool, balancer Balancer, alias, modelID string) (models.Account, bool) {
+	for {
+		var candidates []models.Account
+		for _, account := range accounts {
+			if !tried[account.ID] && AccountBreaker.Available(CircuitKey{AccountID: account.ID, ModelID: modelID}) {
+				candidates = append(candidates, account)
+			}
+		}
+		if len(candidates) == 0 {
+			return models.Account{}, false
+		}
+
+		account := balancer.SelectAccount(alias, highestPriorityTier(candidates))
+		tried[account.ID] = true
+		// Another request may have taken the half-open trial in the meantime
+		if AccountBreaker.Acquire(CircuitKey{AccountID: account.ID, ModelID: modelID}) {
+			return account, true
+		}
+	}
+}
+
+// highestPriorityTier returns the accounts sharing the highest priority value
+func highestPriorityTier(accounts []models.Account) []models.Account {
+	var tier []models.Account
+	for _, account := range accounts {
+		switch {
+		case len(tier) == 0 || account.Priority > tier[0].Priority:
+			tier = []models.Account{account}
+		case account.Priority == tier[0].Priority:
+			tier = append(tier, account)
+		}
+	}
+	return tier
+}
+
+// sortAccountsByID returns a copy of the accounts in a stable order
+func sortAccountsByID(accounts []models.Account) []models.Account {
+	sorted := make([]models.Account, len(accounts))
+	copy(sorted, accounts)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// randomBalancer spreads requests using the global counter
+type randomBalancer struct{}
+
+func (b *randomBalancer) SelectModel(alias string, modelIDs []string) string {
+	return common.GetRandomElement(modelIDs)
+}
+
+func (b *randomBalancer) SelectAccount(alias string, accounts []models.Account) models.Account {
+	return common.GetRandomElement(accounts)
+}
+
+// roundRobinBalancer cycles through models and accounts with a counter per alias
+type roundRobinBalancer struct {
+	counters sync.Map // key -> *uint64
+}
+
+// next returns the next counter value for a key
+func (b *roundRobinBalancer) next(key string) uint64 {
+	value, _ := b.counters.LoadOrStore(key, new(uint64))
+	return atomic.AddUint64(value.(*uint64), 1) - 1
+}
+
+func (b *roundRobinBalancer) SelectModel(alias string, modelIDs []string) string {
+	if len(modelIDs) == 0 {
+		return ""
+	}
+	return modelIDs[b.next("model:"+alias)%uint64(len(modelIDs))]
+}
+
+func (b *roundRobinBalancer) SelectAccount(alias string, accounts []models.Account) models.Account {
+	sorted := sortAccountsByID(accounts)
+	return sorted[b.next("account:"+alias)%uint64(len(sorted))]
+}
+
+// weightedBalancer picks accounts with probability proportional to their weight
+type weightedBalancer struct{}
+
+func (b *weightedBalancer) SelectModel(alias string, modelIDs []string) string {
+	return common.GetRandomElement(modelIDs)
+}
+
+func (b *weightedBalancer) SelectAccount(alias string, accounts []models.Account) models.Account {
+	weights := make([]int, len(accounts))
+	for i, account := range accounts {
+		weights[i] = account.Weight
+	}
+	return accounts[common.GetWeightedRandomIndex(weights)]
+}
+
+// failoverBalancer always prefers the first model and the lowest account ID
+// Later candidates are only used once earlier ones were tried or marked failed
+type failoverBalancer struct{}
+
+func (b *failoverBalancer) SelectModel(alias string, modelIDs []string) string {
+	if len(modelIDs) == 0 {
+		return ""
+	}
+	return modelIDs[0]
+}
+
+func (b *failoverBalancer) OrderModels(alias string, modelIDs []string) []string {
+	return modelIDs
+}
+
+func (b *failoverBalancer) SelectAccount(alias string, accounts []models.Account) models.Account {
+	return sortAccountsByID(accounts)[0]
+}
+
+// leastLatencyBalancer prefers the account with the lowest average response latency
+// Accounts without measurements are tried first so every account gets sampled
+type leastLatencyBalancer struct{}
+
+func (b *leastLatencyBalancer) SelectModel(alias string, modelIDs []string) string {
+	return common.GetRandomElement(modelIDs)
+}
+
+func (b *leastLatencyBalancer) SelectAccount(alias string, accounts []models.Account) models.Account {
+	var best []models.Account
+	var bestLatency int64 = -1
+	for _, account := range accounts {
+		latency, measured := AccountStats.Latency(account.ID)
+		value := int64(latency)
+		if !measured {
+			value = 0
+		}
+		switch {
+		case bestLatency < 0 || value < bestLatency:
+			best = []models.Account{account}
+			bestLatency = value
+		case value == bestLatency:
+			best = append(best, account)
+		}
+	}
+	return common.GetRandomElement(best)
+}
+
+// leastInFlightBalancer prefers the account with the fewest running requests
+type leastInFlightBalancer struct{}
+
+func (b *leastInFlightBalancer) SelectModel(alias string, modelIDs []string) string {
+	return common.GetRandomElement(modelIDs)
+}
+
+func (b *leastInFlightBalancer) SelectAccount(alias string, accounts []models.Account) models.Account {
+	var best []models.Account
+	var bestInFlight int64 = -1
+	for _, account := range accounts {
+		inFlight := AccountStats.InFlight(account.ID)
+		switch {
+		case bestInFlight < 0 || inFlight < bestInFlight:
+			best = []models.Account{account}
+			bestInFlight = inFlight
+		case inFlight == bestInFlight:
+			best = append(best, account)
+		}
+	}
+	return common.GetRandomElement(best)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"air_router/cache"
+	"air_router/db"
+	"air_router/models"
+)
+
+// BudgetGuard pauses accounts whose recorded spend reached their budget until the next budget period
+// Spend is read from the usage records once per period and then kept up to date in memory
+type BudgetGuard struct {
+	UsageDB *db.UsageRecordDB
+
+	mu    sync.Mutex
+	spend map[int]periodSpend
+}
+
+// periodSpend is the spend of an account since the start of its current budget period
+type periodSpend struct {
+	start  time.Time
+	amount float64
+}
+
+// NewBudgetGuard creates a new BudgetGuard
+func NewBudgetGuard(usageDB *db.UsageRecordDB) *BudgetGuard {
+	return &BudgetGuard{
+		UsageDB: usageDB,
+		spend:   make(map[int]periodSpend),
+	}
+}
+
+// budgetPeriod returns the start of an account's current budget period and the start of the next one
+// A total budget never renews, its end is zero
+func budgetPeriod(account models.Account, now time.Time) (time.Time, time.Time) {
+	if account.BudgetPeriod == models.BudgetPeriodTotal {
+		return time.UnixMilli(0), time.Time{}
+	}
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Charge adds the cost of a relayed request to its account's spend and pauses the account once its budget is reached
+// The request's usage record must already be stored
+func (g *BudgetGuard) Charge(account models.Account, cost float64) {
+	if g == nil || account.Budget <= 0 || cost <= 0 {
+		return
+	}
+
+	start, end := budgetPeriod(account, time.Now())
+	g.mu.Lock()
+	entry, ok := g.spend[account.ID]
+	if ok && entry.start.Equal(start) {
+		entry.amount += cost
+		g.spend[account.ID] = entry
+		g.mu.Unlock()
+	} else {
+		// A new period, or the first charge since startup: the stored records already include this request
+		g.mu.Unlock()
+		var err error
+		if entry, err = g.load(account, start); err != nil {
+			slog.Error("error reading account spend", "component", "budget", "account", account.Name, "account_id", account.ID, "error", err)
+			return
+		}
+	}
+
+	g.apply(account, entry.amount, start, end)
+}
+
+// Evaluate re-checks an account against its budget from the stored usage records, e.g. after it was edited
+func (g *BudgetGuard) Evaluate(account models.Account) {
+	if g == nil {
+		return
+	}
+	if account.Budget <= 0 {
+		cache.ResumeAccount(account.ID)
+		return
+	}
+
+	start, end := budgetPeriod(account, time.Now())
+	entry, err := g.load(account, start)
+	if err != nil {
+		slog.Error("error reading account spend", "component", "budget", "account", account.Name, "account_id", account.ID, "error", err)
+		return
+	}
+	g.apply(account, entry.amount, start, end)
+}
+
+// EvaluateAll checks every account against its budget, restoring the pauses after a restart
+func (g *BudgetGuard) EvaluateAll(accountDB *db.AccountDB) {
+	accounts, err := accountDB.GetAccounts()
+	if err != nil {
+		slog.Error("error getting accounts", "component", "budget", "error", err)
+		return
+	}
+	for _, account := range accounts {
+		g.Evaluate(account)
+	}
+}
+
+// load reads an account's spend since start from the usage records and caches it
+func (g *BudgetGuard) load(account models.Account, start time.Time) (periodSpend, error) {
+	amount, err := g.UsageDB.GetAccountSpend(account.ID, start.UnixMilli())
+	if err != nil {
+		return periodSpend{}, err
+	}
+
+	entry := periodSpend{start: start, amount: amount}
+	g.mu.Lock()
+	g.spend[account.ID] = entry
+	g.mu.Unlock()
+	return entry, nil
+}
+
+// apply pauses an account that reached its budget until the period ends, and resumes it otherwise
+func (g *BudgetGuard) apply(account models.Account, spend float64, start, end time.Time) {
+	if spend < account.Budget {
+		cache.ResumeAccount(account.ID)
+		return
+	}
+
+	var reason string
+	if end.IsZero() {
+		reason = fmt.Sprintf("Total budget of $%.2f reached ($%.2f spent)", account.Budget, spend)
+	} else {
+		reason = fmt.Sprintf("Monthly budget of $%.2f reached ($%.2f spent since %s), resumes %s", account.Budget, spend, start.Format(time.DateOnly), end.Format(time.DateOnly))
+	}
+	if _, paused := cache.AccountPauseReason(account.ID); !paused {
+		slog.Warn("pausing account over budget", "component", "budget", "account", account.Name, "account_id", account.ID, "reason", reason)
+	}
+	cache.PauseAccount(account.ID, reason, end)
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"air_router/utils/common"
+)
+
+// CircuitKey identifies a circuit: one upstream model served by one account
+// Keeping circuits per pair stops one broken model from starving other models of the same account
+type CircuitKey struct {
+	AccountID int
+	ModelID   string
+}
+
+// CircuitState represents the state of an account circuit
+type CircuitState string
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects requests until the cool-down elapses
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial request through
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerConfig configures failure thresholds and cool-down times
+type CircuitBreakerConfig struct {
+	FailureThreshold  int           // Consecutive failures that open a closed circuit
+	BaseCooldown      time.Duration // Cool-down after the first trip
+	MaxCooldown       time.Duration // Upper bound of the exponential cool-down
+	ForbiddenCooldown time.Duration // Cool-down after a 402 or 403, which rarely clears within minutes
+}
+
+// LoadCircuitBreakerConfig reads the circuit breaker configuration from the environment
+func LoadCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold:  common.GetEnvIntOrDefault("CIRCUIT_FAILURE_THRESHOLD", 3),
+		BaseCooldown:      time.Duration(common.GetEnvIntOrDefault("CIRCUIT_COOLDOWN_SECONDS", 30)) * time.Second,
+		MaxCooldown:       time.Duration(common.GetEnvIntOrDefault("CIRCUIT_MAX_COOLDOWN_SECONDS", 600)) * time.Second,
+		ForbiddenCooldown: time.Duration(common.GetEnvIntOrDefault("CIRCUIT_FORBIDDEN_COOLDOWN_SECONDS", 3600)) * time.Second,
+	}
+}
+
+// circuit holds the breaker state of one account and model pair
+type circuit struct {
+	state         CircuitState
+	failures      int // Consecutive failures while closed
+	trips         int // Consecutive trips without a successful trial, drives the exponential cool-down
+	openUntil     time.Time
+	trialInFlight bool
+}
+
+// CircuitStatus is a read-only view of a circuit
+type CircuitStatus struct {
+	State     CircuitState `json:"state"`
+	Failures  int          `json:"failures"`
+	Trips     int          `json:"trips"`
+	OpenUntil int64        `json:"open_until,omitempty"` // Unix milliseconds
+}
+
+// CircuitBreaker tracks a closed/open/half-open circuit per account and model pair
+type CircuitBreaker struct {
+	config   CircuitBreakerConfig
+	mu       sync.Mutex
+	circuits map[CircuitKey]*circuit
+}
+
+// AccountBreaker is the circuit breaker shared by every proxy path
+var AccountBreaker = NewCircuitBreaker(LoadCircuitBreakerConfig())
+
+// NewCircuitBreaker creates a new CircuitBreaker
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = 1
+	}
+	if config.MaxCooldown < config.BaseCooldown {
+		config.MaxCooldown = config.BaseCooldown
+	}
+	return &CircuitBreaker{
+		config:   config,
+		circuits: make(map[CircuitKey]*circuit),
+	}
+}
+
+// get returns the circuit of a key, creating a closed one if needed
+// Must be called with b.mu held
+func (b *CircuitBreaker) get(key CircuitKey) *circuit {
+	cb, exists := b.circuits[key]
+	if !exists {
+		cb = &circuit{state: CircuitClosed}
+		b.circuits[key] = cb
+	}
+	return cb
+}
+
+// allows reports whether a circuit would let a request through right now
+func (cb *circuit) allows(now time.Time) bool {
+	switch cb.state {
+	case CircuitOpen:
+		return !now.Before(cb.openUntil)
+	case CircuitHalfOpen:
+		return !cb.trialInFlight
+	default:
+		return true
+	}
+}
+
+// Available reports whether a pair may receive a request, without reserving anything
+func (b *CircuitBreaker) Available(key CircuitKey) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, exists := b.circuits[key]
+	return !exists || cb.allows(time.Now())
+}
+
+// Acquire reserves a request slot for a pair
+// An open circuit whose cool-down elapsed moves to half-open and hands out its single trial
+func (b *CircuitBreaker) Acquire(key CircuitKey) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, exists := b.circuits[key]
+	if !exists {
+		return true
+	}
+	if !cb.allows(time.Now()) {
+		return false
+	}
+	if cb.state != CircuitClosed {
+		cb.state = CircuitHalfOpen
+		cb.trialInFlight = true
+	}
+	return true
+}
+
+// RecordSuccess closes the circuit of a pair
+func (b *CircuitBreaker) RecordSuccess(key CircuitKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A healthy pair needs no entry
+	delete(b.circuits, key)
+}
+
+// RecordFailure counts a failure and opens the circuit once the threshold is reached
+// A failed half-open trial re-opens the circuit immediately with a longer cool-down
+func (b *CircuitBreaker) RecordFailure(key CircuitKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb := b.get(key)
+	cb.failures++
+	if cb.state == CircuitClosed && cb.failures < b.config.FailureThreshold {
+		return
+	}
+	b.trip(cb)
+}
+
+// OpenFor opens the circuit of a pair for a fixed time, e.g. until an upstream rate limit resets
+// The exponential trip counter is left alone since the wait comes from the upstream
+func (b *CircuitBreaker) OpenFor(key CircuitKey, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb := b.get(key)
+	until := time.Now().Add(d)
+	if cb.state == CircuitOpen && cb.openUntil.After(until) {
+		return
+	}
+	cb.state = CircuitOpen
+	cb.openUntil = until
+	cb.trialInFlight = false
+}
+
+// ForbiddenCooldown returns how long a pair stays open after the account refused the model
+func (b *CircuitBreaker) ForbiddenCooldown() time.Duration {
+	return b.config.ForbiddenCooldown
+}
+
+// Release returns a slot taken by Acquire without judging the pair, so a half-open trial can be handed out again
+func (b *CircuitBreaker) Release(key CircuitKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cb, exists := b.circuits[key]; exists {
+		cb.trialInFlight = false
+	}
+}
+
+// trip opens a circuit with an exponential cool-down
+// Must be called with b.mu held
+func (b *CircuitBreaker) trip(cb *circuit) {
+	cooldown := b.config.BaseCooldown
+	for i := 0; i < cb.trips && cooldown < b.config.MaxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > b.config.MaxCooldown {
+		cooldown = b.config.MaxCooldown
+	}
+
+	cb.state = CircuitOpen
+	cb.trips++
+	cb.openUntil = time.Now().Add(cooldown)
+	cb.trialInFlight = false
+}
+
+// Status returns the current state of a pair circuit
+func (b *CircuitBreaker) Status(key CircuitKey) CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, exists := b.circuits[key]
+	if !exists {
+		return CircuitStatus{State: CircuitClosed}
+	}
+	return cb.status()
+}
+
+// status returns a read-only view of a circuit
+func (cb *circuit) status() CircuitStatus {
+	status := CircuitStatus{
+		State:    cb.state,
+		Failures: cb.failures,
+		Trips:    cb.trips,
+	}
+	if cb.state == CircuitOpen {
+		status.OpenUntil = cb.openUntil.UnixMilli()
+	}
+	return status
+}
+
+// Snapshot returns the current state of every circuit that has seen traffic
+func (b *CircuitBreaker) Snapshot() map[CircuitKey]CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := make(map[CircuitKey]CircuitStatus, len(b.circuits))
+	for key, cb := range b.circuits {
+		snapshot[key] = cb.status()
+	}
+	return snapshot
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"air_router/models"
+	"air_router/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyRequest describes the client request raced across accounts
+type ProxyRequest struct {
+	Provider models.Provider // Provider of the requested alias
+	ModelID  string          // Upstream model ID
+	Path     string
+	Body     []byte
+	Stream   bool
+}
+
+// Leg is one upstream attempt of a request, possibly racing a hedged duplicate
+type Leg struct {
+	Account models.Account
+	Key     CircuitKey
+	Hedged  bool // Started because an earlier leg was slow to answer
+	Resp    *http.Response
+	Success bool
+	Body    []byte       // Body of a failed response
+	Failure FailureClass // Outcome the response does not tell, such as a stream abandoned for being slow
+
+	cancel context.CancelFunc
+}
+
+// startLeg sends the request of a leg in the background and reports the leg on done once it has an outcome
+// For streams a success only counts once the first event arrived
+func (s *ProxyService) startLeg(c *gin.Context, leg *Leg, req ProxyRequest, done chan<- *Leg) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	leg.cancel = cancel
+	AccountStats.Begin(leg.Account.ID)
+
+	go func() {
+		start := time.Now()
+		s.sendLeg(ctx, c, leg, req)
+
+		// A stream that stays silent is abandoned before anything reaches the client
+		if leg.Success && req.Stream && Retry.FirstEventTimeout > 0 {
+			if err := utils.WaitForFirstEvent(leg.Resp, Retry.FirstEventTimeout); err != nil {
+				slog.WarnContext(c, "abandoning slow stream", "account", leg.Account.Name, "account_id", leg.Account.ID, "error", err)
+				leg.Resp, leg.Success = nil, false
+				if errors.Is(err, utils.ErrFirstEventTimeout) {
+					leg.Failure = FailureSlowStream
+				}
+			}
+		}
+		if leg.Success || leg.Resp == nil {
+			AccountStats.ObserveLatency(leg.Account.ID, time.Since(start))
+		}
+		done <- leg
+	}()
+}
+
+// sendLeg sends the request of a leg to its account, translating it when the account speaks another protocol
+func (s *ProxyService) sendLeg(ctx context.Context, c *gin.Context, leg *Leg, req ProxyRequest) {
+	translation := SelectTranslation(req.Provider, req.Path, leg.Account)
+	leg.Resp, leg.Success, leg.Body = s.TryWithTranslation(ctx, c, leg.Account, translation, req.Path, req.Body)
+}
+
+// Release closes the response of the winning leg and cancels its context once the response was relayed
+func (l *Leg) Release() {
+	l.Resp.Body.Close()
+	l.cancel()
+}
+
+// abandon cancels a leg that lost the race; its account is neither rewarded nor penalized
+// The attempt is still noted for the request since the upstream received it and may bill for it
+func (l *Leg) abandon(c *gin.Context) {
+	l.cancel()
+	if l.Resp != nil && l.Success {
+		l.Resp.Body.Close()
+	}
+	AccountBreaker.Release(l.Key)
+	AccountStats.End(l.Account.ID)
+	NoteAttempt(c, l.Account, l.Key.ModelID, l.Resp, FailureHedgeLost)
+}
+
+// RaceAccounts sends a request to the first account and, when hedgeDelay is positive and no answer arrived by then,
+// sends the same request to a second account obtained from next. The first successful leg wins and the other one
+// is canceled. Returns the winner (nil when every leg failed) and the failed legs in completion order.
+// The caller owns the returned legs and must end their AccountStats entries and Release the winner
+func (s *ProxyService) RaceAccounts(c *gin.Context, req ProxyRequest, first models.Account, hedgeDelay time.Duration, next func() (models.Account, bool)) (*Leg, []*Leg) {
+	done := make(chan *Leg, 2)
+	running := map[*Leg]bool{}
+
+	leg := &Leg{Account: first, Key: CircuitKey{AccountID: first.ID, ModelID: req.ModelID}}
+	s.startLeg(c, leg, req, done)
+	running[leg] = true
+
+	var hedgeTimer <-chan time.Time
+	if hedgeDelay > 0 {
+		timer := time.NewTimer(hedgeDelay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+
+	var winner *Leg
+	var failed []*Leg
+	for len(running) > 0 && winner == nil {
+		select {
+		case leg := <-done:
+			delete(running, leg)
+			if leg.Success {
+				winner = leg
+			} else {
+				// A failed leg's body was already read, its request has nothing left to do
+				leg.cancel()
+				failed = append(failed, leg)
+			}
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			account, ok := next()
+			if !ok {
+				continue
+			}
+			slog.InfoContext(c, "hedging request", "model", req.ModelID, "account", account.Name, "account_id", account.ID, "delay", hedgeDelay)
+			hedge := &Leg{Account: account, Key: CircuitKey{AccountID: account.ID, ModelID: req.ModelID}, Hedged: true}
+			s.startLeg(c, hedge, req, done)
+			running[hedge] = true
+		}
+	}
+
+	// Cancel the losers and wait for them so no goroutine outlives the request
+	for leg := range running {
+		leg.cancel()
+	}
+	for range running {
+		(<-done).abandon(c)
+	}
+
+	return winner, failed
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"air_router/constants"
+	"air_router/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRaceAccountsRecordsSlowStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The upstream answers with stream headers but never sends an event
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	previous := Retry
+	Retry.FirstEventTimeout = 50 * time.Millisecond
+	defer func() { Retry = previous }()
+
+	body := `{"model":"slow-model","stream":true,"messages":[]}`
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+
+	account := models.Account{ID: 9001, Name: "slow", BaseURL: upstream.URL, Protocol: models.DefaultAccountProtocol}
+	req := ProxyRequest{ModelID: "slow-model", Path: "/chat/completions", Body: []byte(body), Stream: true}
+
+	service := NewProxyService(nil, nil)
+	winner, failed := service.RaceAccounts(c, req, account, 0, nil)
+	if winner != nil {
+		t.Fatal("expected no winner for a silent stream")
+	}
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 failed leg, got %d", len(failed))
+	}
+	defer AccountStats.End(account.ID)
+
+	if failure := service.RecordLeg(c, failed[0]); failure != FailureSlowStream {
+		t.Fatalf("expected failure %q, got %q", FailureSlowStream, failure)
+	}
+	if !FailureSlowStream.Retryable() {
+		t.Fatal("a slow stream should be retried on another account")
+	}
+
+	value, _ := c.Get(constants.ContextKeyAttempts)
+	attempts, _ := value.([]models.RequestAttempt)
+	if len(attempts) != 1 || attempts[0].Failure != string(FailureSlowStream) {
+		t.Fatalf("expected one slow_stream attempt, got %+v", attempts)
+	}
+	if status := AccountBreaker.Status(failed[0].Key); status.Failures != 1 {
+		t.Fatalf("expected the slow stream to count against the circuit, got %+v", status)
+	}
+}
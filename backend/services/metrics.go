@@ -0,0 +1,244 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"air_router/cache"
+	"air_router/models"
+)
+
+// Histogram buckets in seconds
+var (
+	requestDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	firstByteBuckets       = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+)
+
+// circuitStateValues maps circuit states to the value of the circuit state gauge
+var circuitStateValues = map[CircuitState]int{
+	CircuitClosed:   0,
+	CircuitHalfOpen: 1,
+	CircuitOpen:     2,
+}
+
+// ProxyMetrics collects traffic metrics of the proxy, exposed in the Prometheus text format
+type ProxyMetrics struct {
+	requests       *metricVec
+	upstreamErrors *metricVec
+	attempts       *metricVec
+	duration       *metricVec
+	firstByte      *metricVec
+}
+
+// Metrics is the global proxy metrics collector
+var Metrics = NewProxyMetrics()
+
+// NewProxyMetrics creates a new ProxyMetrics
+func NewProxyMetrics() *ProxyMetrics {
+	routeLabels := []string{"alias", "model", "account"}
+	return &ProxyMetrics{
+		requests:       newMetricVec("air_router_requests_total", "Proxied requests by status returned to the client.", "counter", append(routeLabels, "code"), nil),
+		upstreamErrors: newMetricVec("air_router_upstream_errors_total", "Failed upstream attempts by upstream status, 0 when the account gave no response.", "counter", append(routeLabels, "status"), nil),
+		attempts:       newMetricVec("air_router_upstream_attempts_total", "Upstream attempts, including retries and hedges, lost hedges too.", "counter", routeLabels, nil),
+		duration:       newMetricVec("air_router_request_duration_seconds", "Time from receiving a request to relaying the last byte of its response.", "histogram", routeLabels, requestDurationBuckets),
+		firstByte:      newMetricVec("air_router_time_to_first_byte_seconds", "Time from receiving a request to relaying the first byte of its response.", "histogram", routeLabels, firstByteBuckets),
+	}
+}
+
+// ObserveRequest records a finished request
+// Its alias, model and account are those of the last attempt; attempts are labelled with their own account and model
+// firstByte is 0 when nothing was relayed
+func (m *ProxyMetrics) ObserveRequest(alias, model, account string, status int, attempts []models.RequestAttempt, duration, firstByte time.Duration) {
+	m.requests.add(1, alias, model, account, strconv.Itoa(status))
+	m.duration.observe(duration.Seconds(), alias, model, account)
+	if firstByte > 0 {
+		m.firstByte.observe(firstByte.Seconds(), alias, model, account)
+	}
+	for _, attempt := range attempts {
+		m.attempts.add(1, alias, attempt.UpstreamModel, attempt.AccountName)
+		// A hedge that lost the race did not fail
+		if attempt.Failure != "" && attempt.Failure != string(FailureHedgeLost) {
+			m.upstreamErrors.add(1, alias, attempt.UpstreamModel, attempt.AccountName, strconv.Itoa(attempt.Status))
+		}
+	}
+}
+
+// WritePrometheus writes the traffic metrics followed by the models cache and circuit gauges
+func (m *ProxyMetrics) WritePrometheus(w io.Writer) {
+	for _, vec := range []*metricVec{m.requests, m.upstreamErrors, m.attempts, m.duration, m.firstByte} {
+		vec.write(w)
+	}
+
+	writeGauge(w, "air_router_cached_models", "Upstream models in the models cache.", float64(len(cache.GetAllModels())))
+
+	refresh := cache.GetLastRefreshStatus()
+	if !refresh.FinishedAt.IsZero() {
+		success := 0.0
+		if refresh.Success {
+			success = 1
+		}
+		writeGauge(w, "air_router_models_refresh_duration_seconds", "Duration of the last models cache refresh.", refresh.Duration.Seconds())
+		writeGauge(w, "air_router_models_refresh_success", "Whether the last models cache refresh rebuilt the cache.", success)
+		writeGauge(w, "air_router_models_refresh_failed_accounts", "Accounts whose models could not be fetched by the last refresh.", float64(refresh.FailedAccounts))
+		writeGauge(w, "air_router_models_refresh_timestamp_seconds", "Unix time of the last models cache refresh.", float64(refresh.FinishedAt.Unix()))
+	}
+
+	writeCircuitStates(w)
+}
+
+// writeCircuitStates writes the state of every circuit, labelled by account and upstream model
+func writeCircuitStates(w io.Writer) {
+	names := make(map[int]string)
+	for _, account := range cache.GetAllAccounts() {
+		names[account.ID] = account.Name
+	}
+
+	snapshot := AccountBreaker.Snapshot()
+	keys := make([]CircuitKey, 0, len(snapshot))
+	for key := range snapshot {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].AccountID != keys[j].AccountID {
+			return keys[i].AccountID < keys[j].AccountID
+		}
+		return keys[i].ModelID < keys[j].ModelID
+	})
+
+	fmt.Fprintf(w, "# HELP air_router_circuit_state Circuit state of an account and upstream model pair: 0 closed, 1 half open, 2 open.\n# TYPE air_router_circuit_state gauge\n")
+	for _, key := range keys {
+		name, ok := names[key.AccountID]
+		if !ok {
+			name = strconv.Itoa(key.AccountID)
+		}
+		labels := formatLabels([]string{"account_id", "account", "model"}, []string{strconv.Itoa(key.AccountID), name, key.ModelID})
+		fmt.Fprintf(w, "air_router_circuit_state%s %d\n", labels, circuitStateValues[snapshot[key].State])
+	}
+}
+
+// writeGauge writes an unlabelled gauge
+func writeGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatValue(value))
+}
+
+// metricVec is a counter or histogram with one series per combination of label values
+type metricVec struct {
+	name    string
+	help    string
+	kind    string // counter or histogram
+	labels  []string
+	buckets []float64 // upper bounds of a histogram, +Inf excluded
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series holds the value of a counter, or the buckets, sum and count of a histogram
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64 // per bucket, not cumulative
+	count       uint64
+}
+
+func newMetricVec(name, help, kind string, labels []string, buckets []float64) *metricVec {
+	return &metricVec{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// get returns the series of the given label values, creating it if needed
+// Must be called with v.mu held
+func (v *metricVec) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, exists := v.series[key]
+	if !exists {
+		s = &series{labelValues: labelValues, counts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	return s
+}
+
+// add increments a counter
+func (v *metricVec) add(delta float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value += delta
+}
+
+// observe records a histogram sample
+func (v *metricVec) observe(sample float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.get(labelValues)
+	for i, bound := range v.buckets {
+		if sample <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.value += sample
+	s.count++
+}
+
+// write writes every series in the Prometheus text exposition format, sorted by labels
+func (v *metricVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := v.series[key]
+		if v.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.labelValues), formatValue(s.value))
+			continue
+		}
+
+		bucketLabels := append(append([]string{}, v.labels...), "le")
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(bucketLabels, append(append([]string{}, s.labelValues...), formatValue(bound))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(bucketLabels, append(append([]string{}, s.labelValues...), "+Inf")), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.labelValues), formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.labelValues), s.count)
+	}
+}
+
+// labelValueEscaper escapes label values as required by the text exposition format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders {name="value",...}
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelValueEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatValue renders a sample value in the shortest exact form
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"log/slog"
+
+	"air_router/db"
+	"air_router/models"
+	"air_router/utils"
+)
+
+// Pricing computes the cost of requests from the model price catalog and the account multipliers
+type Pricing struct {
+	PriceDB *db.PriceDB
+}
+
+// NewPricing creates a new Pricing
+func NewPricing(priceDB *db.PriceDB) *Pricing {
+	return &Pricing{
+		PriceDB: priceDB,
+	}
+}
+
+// Cost returns the cost in USD of a request served by account for an upstream model
+// Models without a catalog price cost nothing
+func (p *Pricing) Cost(account models.Account, modelID string, usage utils.Usage) float64 {
+	if p == nil || p.PriceDB == nil || modelID == "" || usage.TotalTokens() == 0 {
+		return 0
+	}
+
+	price, found, err := p.PriceDB.GetPriceForModel(modelID)
+	if err != nil {
+		slog.Error("error looking up model price", "component", "pricing", "model", modelID, "error", err)
+		return 0
+	}
+	if !found {
+		return 0
+	}
+
+	multiplier := account.CostMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	return price.Cost(usage.PromptTokens, usage.CompletionTokens, usage.CachedTokens) * multiplier
+}
@@ -1,17 +1,20 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"io"
-	"log"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"strings"
+	"time"
 
 	"air_router/cache"
+	"air_router/constants"
+	"air_router/db"
 	"air_router/models"
 	"air_router/utils"
-	"air_router/utils/common"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,8 +27,8 @@ func GetGlobalAccountCounter() *uint64 {
 	return &globalAccountCounter
 }
 
-// Claude API paths that require special handling
-var claudePaths = []string{"/messages", "/messages/batches", "/files", "/skills"}
+// Paths only the Claude API serves; shared paths such as /files are told apart by the anthropic-version header
+var claudePaths = []string{"/messages", "/skills"}
 
 func init() {
 	// Initialize with a secure random number between 10w and 20w
@@ -35,114 +38,245 @@ func init() {
 
 // ProxyService handles proxy request routing and retry logic
 type ProxyService struct {
-	HTTPClient *http.Client
+	AccountDB *db.AccountDB
+	Affinity  *ResourceAffinity
 }
 
 // NewProxyService creates a new ProxyService
-func NewProxyService() *ProxyService {
+func NewProxyService(accountDB *db.AccountDB, affinity *ResourceAffinity) *ProxyService {
 	return &ProxyService{
-		HTTPClient: utils.HTTPClient,
+		AccountDB: accountDB,
+		Affinity:  affinity,
 	}
 }
 
-// IsClaudeAPI checks if the path is a Claude API endpoint
-func IsClaudeAPI(path string) bool {
+// IsClaudeAPI checks if a request targets the Claude API: a /messages or /skills path, or any path sent with anthropic-version
+func IsClaudeAPI(path string, header http.Header) bool {
+	path, _, _ = strings.Cut(path, "?")
 	for _, claudePath := range claudePaths {
-		if strings.Contains(path, claudePath) {
+		if path == claudePath || strings.HasPrefix(path, claudePath+"/") {
 			return true
 		}
 	}
-	return false
+	return header.Get("anthropic-version") != ""
+}
+
+// IsGeminiAPI checks if the path is a Gemini native endpoint: models/{model}:{method}
+func IsGeminiAPI(path string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(path, "/"), "models/") && strings.Contains(path, ":")
+}
+
+// FilterAccountsForPath keeps the accounts able to serve a request for a path natively or through a translation
+func FilterAccountsForPath(accounts []models.Account, path string, header http.Header) []models.Account {
+	var result []models.Account
+	for _, account := range accounts {
+		if accountServesPath(account, path, header) {
+			result = append(result, account)
+		}
+	}
+	return result
+}
+
+// ClaudeAccounts keeps the accounts able to serve the Claude API as-is
+func ClaudeAccounts(accounts []models.Account) []models.Account {
+	var result []models.Account
+	for _, account := range accounts {
+		if account.ClaudeAvailable {
+			result = append(result, account)
+		}
+	}
+	return result
+}
+
+// accountServesPath reports whether the protocol of an account can serve a request for a path
+func accountServesPath(account models.Account, path string, header http.Header) bool {
+	switch account.Protocol {
+	case models.ProtocolGemini:
+		return IsGeminiAPI(path)
+	case models.ProtocolAnthropic:
+		return IsClaudeAPI(path, header) || strings.Trim(path, "/") == "chat/completions"
+	default:
+		return !IsGeminiAPI(path)
+	}
 }
 
 // TryWithAccount attempts to forward request to a specific account
+// The upstream request is bound to the client's context and to the account timeouts,
+// and waits for response headers no longer than the remaining request budget
 func (s *ProxyService) TryWithAccount(c *gin.Context, account models.Account, path string, bodyBytes []byte, headers http.Header) (*http.Response, bool, []byte) {
+	return s.TryWithAccountContext(c.Request.Context(), c, account, path, bodyBytes, headers)
+}
+
+// TryWithAccountContext is TryWithAccount bound to ctx, which must derive from the client's context
+func (s *ProxyService) TryWithAccountContext(parent context.Context, c *gin.Context, account models.Account, path string, bodyBytes []byte, headers http.Header) (*http.Response, bool, []byte) {
 	targetURL := utils.BuildTargetURL(account, path)
 
-	isClaude := IsClaudeAPI(path)
-	req, err := utils.CreateProxyRequest(c.Request.Method, targetURL, bodyBytes, account, headers, isClaude)
+	timeouts := utils.AccountTimeouts(account)
+	if remaining, ok := RemainingBudget(c); ok && (timeouts.FirstByte <= 0 || remaining < timeouts.FirstByte) {
+		if remaining <= 0 {
+			return nil, false, nil
+		}
+		timeouts.FirstByte = remaining
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	isClaude := account.Protocol == models.ProtocolAnthropic || IsClaudeAPI(path, headers)
+	req, err := utils.CreateProxyRequest(ctx, c.Request.Method, targetURL, bodyBytes, account, headers, isClaude)
 	if err != nil {
+		cancel()
 		return nil, false, nil
 	}
 
-	resp, err := s.HTTPClient.Do(req)
+	resp, err := utils.DoWithTimeouts(utils.ClientForTimeouts(timeouts), req, cancel, timeouts)
 	if err != nil {
-		log.Printf("[TryWithAccount /v1%s] request error from account %s (ID: %d)", path, account.Name, account.ID)
+		slog.WarnContext(c, "upstream request error", "route", "/v1"+path, "account", account.Name, "account_id", account.ID, "error", err)
 		return nil, false, nil
 	}
 
 	// Check status code
-	if resp.StatusCode != 200 {
+	if ClassifyResponse(resp) != FailureNone {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		log.Printf("[TryWithAccount /v1%s] response error : %s", path, string(bodyBytes))
+		slog.WarnContext(c, "upstream response error", "route", "/v1"+path, "account", account.Name, "account_id", account.ID, "status", resp.StatusCode, "body", string(bodyBytes))
 		return resp, false, bodyBytes
 	}
 
 	return resp, true, nil
 }
 
+// RecordAttempt applies the retry policy to the outcome of an attempt on an account
+// Returns the failure class; the caller retries elsewhere only when it is retryable
+func (s *ProxyService) RecordAttempt(c *gin.Context, account models.Account, key CircuitKey, resp *http.Response) FailureClass {
+	class := ClassifyResponse(resp)
+	if resp == nil && c.Request.Context().Err() != nil {
+		// The client disconnected, the account is not to blame
+		class = FailureCanceled
+	}
+	s.recordOutcome(c, account, key, resp, class)
+	return class
+}
+
+// RecordLeg is RecordAttempt for a raced leg, keeping the outcome the leg settled on itself when it has one
+func (s *ProxyService) RecordLeg(c *gin.Context, leg *Leg) FailureClass {
+	if leg.Failure == FailureNone {
+		return s.RecordAttempt(c, leg.Account, leg.Key, leg.Resp)
+	}
+	s.recordOutcome(c, leg.Account, leg.Key, leg.Resp, leg.Failure)
+	return leg.Failure
+}
+
+// recordOutcome notes an attempt for the request and updates the pair circuit according to its failure class
+func (s *ProxyService) recordOutcome(c *gin.Context, account models.Account, key CircuitKey, resp *http.Response, class FailureClass) {
+	// The last attempt is the one the request's usage record is accounted to
+	NoteAttempt(c, account, key.ModelID, resp, class)
+
+	switch class {
+	case FailureNone:
+		AccountBreaker.RecordSuccess(key)
+	case FailureClient, FailureCanceled:
+		AccountBreaker.Release(key)
+	case FailureAuth:
+		AccountBreaker.Release(key)
+		s.disableAccount(c, account, resp.StatusCode)
+	case FailureForbidden:
+		slog.WarnContext(c, "account refused the model, cooling down", "account", account.Name, "account_id", account.ID, "model", key.ModelID, "status", resp.StatusCode, "cooldown", AccountBreaker.ForbiddenCooldown())
+		AccountBreaker.OpenFor(key, AccountBreaker.ForbiddenCooldown())
+	case FailureRateLimit:
+		if wait := ParseRetryAfter(resp.Header, time.Now()); wait > 0 {
+			slog.WarnContext(c, "account rate limited, cooling down", "account", account.Name, "account_id", account.ID, "model", key.ModelID, "cooldown", wait)
+			AccountBreaker.OpenFor(key, wait)
+		} else {
+			AccountBreaker.RecordFailure(key)
+		}
+	default:
+		AccountBreaker.RecordFailure(key)
+	}
+}
+
+// disableAccount disables an account whose credentials were rejected and drops it from the models cache
+func (s *ProxyService) disableAccount(ctx context.Context, account models.Account, statusCode int) {
+	slog.WarnContext(ctx, "disabling account after credentials were rejected", "account", account.Name, "account_id", account.ID, "status", statusCode)
+	cache.RemoveAccount(account.ID)
+	if s.AccountDB == nil {
+		return
+	}
+	if err := s.AccountDB.SetAccountEnabled(account.ID, false); err != nil {
+		slog.ErrorContext(ctx, "error disabling account", "account", account.Name, "account_id", account.ID, "error", err)
+	}
+}
+
 // TryWithRetryModel attempts to forward request using accounts that support the model
 // Returns (success, lastResponse, lastResponseBody)
 func (s *ProxyService) TryWithRetryModel(c *gin.Context, path string, modelID string, bodyBytes []byte) (bool, *http.Response, []byte) {
-	accounts := cache.GetAccountsForModel(modelID)
+	accounts := FilterAccountsForPath(cache.GetAccountsForModel(modelID), path, c.Request.Header)
 	if len(accounts) == 0 {
 		return false, nil, nil
 	}
 
-	// Check if this is a Claude API request
-	isClaude := IsClaudeAPI(path)
-	if isClaude {
-		log.Printf("[ProxyService] Claude API detected, filtering claude_available accounts")
-	}
+	// Requests referencing a stored object go to the account that created it
+	accounts, pinned := s.Affinity.Pin(c, accounts, path, bodyBytes)
 
-	// Filter accounts for Claude API if needed
-	var availableAccounts []models.Account
-	if isClaude {
-		for _, acc := range accounts {
-			if acc.ClaudeAvailable {
-				availableAccounts = append(availableAccounts, acc)
-			}
-		}
-		if len(availableAccounts) == 0 {
-			log.Printf("[ProxyService] No Claude available accounts found for model %s", modelID)
+	// Check if this is a Claude API request
+	isClaude := IsClaudeAPI(path, c.Request.Header)
+	if isClaude && !pinned {
+		slog.DebugContext(c, "Claude API detected, filtering claude_available accounts")
+		accounts = ClaudeAccounts(accounts)
+		if len(accounts) == 0 {
+			slog.InfoContext(c, "no claude_available accounts for model", "model", modelID)
 			return false, nil, nil
 		}
-		accounts = availableAccounts
 	}
 
-	log.Printf("[ProxyService] Model: %s, Accounts: %d, IsClaude: %v", modelID, len(accounts), isClaude)
+	slog.InfoContext(c, "routing by upstream model", "model", modelID, "accounts", len(accounts), "claude", isClaude)
 
-	// Retry at most 2 times
-	maxAttempts := 2
-	if len(accounts) < 2 {
-		maxAttempts = len(accounts)
-	}
+	maxAttempts := Retry.Attempts(Retry.MaxAttemptsDirect, len(accounts))
 
 	var lastResp *http.Response
 	var lastRespBody []byte
+	triedAccounts := make(map[int]bool)
+	balancer := GetBalancer(models.DefaultRoutingStrategy)
+
+	for attempt := 0; attempt < maxAttempts && !BudgetExhausted(c); attempt++ {
+		account, ok := SelectAccount(accounts, triedAccounts, balancer, modelID, modelID)
+		if !ok {
+			slog.InfoContext(c, "no available accounts left for model", "model", modelID)
+			break
+		}
+
+		slog.InfoContext(c, "direct attempt", "attempt", attempt+1, "max_attempts", maxAttempts, "account", account.Name, "account_id", account.ID, "model", modelID)
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		// Use common function to get random index and handle counter reset
-		accountIndex := common.GetRandomIndex(len(accounts))
-		account := accounts[accountIndex]
-
-		log.Printf("[ProxyService] Attempt %d/%d with account %s (ID: %d)", attempt+1, maxAttempts, account.Name, account.ID)
-
-		resp, success, respBody := s.TryWithAccount(c, account, path, bodyBytes, c.Request.Header)
-		if resp != nil {
-			// Keep track of last response
-			lastResp = resp
-			lastRespBody = respBody
-
-			defer resp.Body.Close()
-			if success {
-				// Stream response
-				utils.StreamResponse(c, resp)
-				log.Printf("[ProxyService] Success with account %s (ID: %d)", account.BaseURL, account.ID)
-				return true, nil, nil
+		circuitKey := CircuitKey{AccountID: account.ID, ModelID: modelID}
+		// Direct requests name no alias provider, only the account protocol selects a translation
+		translation := SelectTranslation("", path, account)
+		resp, success, respBody := s.TryWithTranslation(c.Request.Context(), c, account, translation, path, bodyBytes)
+		failure := s.RecordAttempt(c, account, circuitKey, resp)
+		if resp == nil {
+			if !failure.Retryable() {
+				break
 			}
+			continue
 		}
+
+		// Keep track of last response
+		lastResp = resp
+		lastRespBody = respBody
+
+		if !success {
+			if !failure.Retryable() {
+				// The client's own error is relayed as-is without trying other accounts
+				break
+			}
+			continue
+		}
+
+		s.Affinity.Track(c, account, c.Request.Method, path, resp)
+		defer resp.Body.Close()
+
+		// Stream response
+		SetRoutingHeaders(c)
+		c.Set(constants.ContextKeyUsage, utils.StreamResponse(c, resp))
+		slog.InfoContext(c, "request served", "account", account.Name, "account_id", account.ID, "model", modelID)
+		return true, nil, nil
 	}
 
 	return false, lastResp, lastRespBody
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"air_router/db"
+	"air_router/models"
+)
+
+// RateLimiter enforces per-key request and token limits
+// Counters are kept in fixed UTC windows (minute, day, month) persisted in SQLite
+type RateLimiter struct {
+	APIKeyDB *db.APIKeyDB
+
+	keyLocks sync.Map // api key ID -> *sync.Mutex, serializing the check and count of one key
+
+	mu               sync.Mutex
+	lastPrunedMinute string
+}
+
+// RateLimitResult is the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed    bool
+	Message    string
+	RetryAfter time.Duration
+	Headers    map[string]string
+}
+
+// NewRateLimiter creates a new RateLimiter
+func NewRateLimiter(apiKeyDB *db.APIKeyDB) *RateLimiter {
+	return &RateLimiter{
+		APIKeyDB: apiKeyDB,
+	}
+}
+
+// usagePeriods returns the current period keys for every usage period
+func usagePeriods(now time.Time) map[string]string {
+	now = now.UTC()
+	return map[string]string{
+		models.UsagePeriodMinute: now.Format("2006-01-02T15:04"),
+		models.UsagePeriodDay:    now.Format("2006-01-02"),
+		models.UsagePeriodMonth:  now.Format("2006-01"),
+	}
+}
+
+// periodResets returns the time left until each usage period rolls over
+func periodResets(now time.Time) map[string]time.Duration {
+	now = now.UTC()
+	nextMinute := now.Truncate(time.Minute).Add(time.Minute)
+	nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	return map[string]time.Duration{
+		models.UsagePeriodMinute: nextMinute.Sub(now),
+		models.UsagePeriodDay:    nextDay.Sub(now),
+		models.UsagePeriodMonth:  nextMonth.Sub(now),
+	}
+}
+
+// Allow checks the limits of an api key and, when allowed, counts the request
+// Token quotas are checked against tokens already consumed in the current period
+// Only requests of the same key wait for each other
+func (r *RateLimiter) Allow(apiKey models.APIKey) RateLimitResult {
+	lock := r.keyLock(apiKey.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	now := time.Now()
+	periods := usagePeriods(now)
+	resets := periodResets(now)
+
+	if apiKey.RPMLimit <= 0 && apiKey.TokensPerDayLimit <= 0 && apiKey.MonthlyTokenLimit <= 0 {
+		r.countRequest(apiKey, periods)
+		return RateLimitResult{Allowed: true}
+	}
+
+	counters, err := r.APIKeyDB.GetAPIKeyUsage(apiKey.ID, periods)
+	if err != nil {
+		// Fail open: a broken counter store should not take the proxy down
+		slog.Error("error reading key usage", "component", "rate_limiter", "api_key", apiKey.Name, "api_key_id", apiKey.ID, "error", err)
+		r.countRequest(apiKey, periods)
+		return RateLimitResult{Allowed: true}
+	}
+
+	minute := counters[models.UsagePeriodMinute]
+	day := counters[models.UsagePeriodDay]
+	month := counters[models.UsagePeriodMonth]
+
+	result := RateLimitResult{Allowed: true, Headers: make(map[string]string)}
+
+	if apiKey.RPMLimit > 0 {
+		remaining := apiKey.RPMLimit - minute.Requests - 1
+		if remaining < 0 {
+			remaining = 0
+		}
+		result.Headers["x-ratelimit-limit-requests"] = strconv.FormatInt(apiKey.RPMLimit, 10)
+		result.Headers["x-ratelimit-remaining-requests"] = strconv.FormatInt(remaining, 10)
+		result.Headers["x-ratelimit-reset-requests"] = formatReset(resets[models.UsagePeriodMinute])
+
+		if minute.Requests >= apiKey.RPMLimit {
+			result.deny(fmt.Sprintf("Rate limit reached: %d requests per minute", apiKey.RPMLimit), resets[models.UsagePeriodMinute])
+		}
+	}
+
+	// Report the daily token quota when set, otherwise the monthly one
+	tokenLimit, tokensUsed, tokenReset := apiKey.TokensPerDayLimit, day.Tokens, resets[models.UsagePeriodDay]
+	if tokenLimit <= 0 {
+		tokenLimit, tokensUsed, tokenReset = apiKey.MonthlyTokenLimit, month.Tokens, resets[models.UsagePeriodMonth]
+	}
+	if tokenLimit > 0 {
+		remaining := tokenLimit - tokensUsed
+		if remaining < 0 {
+			remaining = 0
+		}
+		result.Headers["x-ratelimit-limit-tokens"] = strconv.FormatInt(tokenLimit, 10)
+		result.Headers["x-ratelimit-remaining-tokens"] = strconv.FormatInt(remaining, 10)
+		result.Headers["x-ratelimit-reset-tokens"] = formatReset(tokenReset)
+	}
+
+	if apiKey.TokensPerDayLimit > 0 && day.Tokens >= apiKey.TokensPerDayLimit {
+		result.deny(fmt.Sprintf("Daily token quota reached: %d tokens per day", apiKey.TokensPerDayLimit), resets[models.UsagePeriodDay])
+	}
+	if apiKey.MonthlyTokenLimit > 0 && month.Tokens >= apiKey.MonthlyTokenLimit {
+		result.deny(fmt.Sprintf("Monthly token quota reached: %d tokens per month", apiKey.MonthlyTokenLimit), resets[models.UsagePeriodMonth])
+	}
+
+	if result.Allowed {
+		r.countRequest(apiKey, periods)
+	}
+	return result
+}
+
+// deny marks the result as rejected, keeping the longest wait when several limits are hit
+func (res *RateLimitResult) deny(message string, retryAfter time.Duration) {
+	if !res.Allowed && res.RetryAfter >= retryAfter {
+		return
+	}
+	res.Allowed = false
+	res.Message = message
+	res.RetryAfter = retryAfter
+}
+
+// RecordUsage adds consumed tokens and hedged duplicate requests to the day and month counters of an api key
+func (r *RateLimiter) RecordUsage(apiKey models.APIKey, tokens, hedgedRequests int64) {
+	if tokens <= 0 && hedgedRequests <= 0 {
+		return
+	}
+
+	periods := usagePeriods(time.Now())
+	delete(periods, models.UsagePeriodMinute)
+	delta := models.UsageCounter{Tokens: tokens, HedgedRequests: hedgedRequests}
+	if err := r.APIKeyDB.AddAPIKeyUsage(apiKey.ID, periods, delta); err != nil {
+		slog.Error("error recording key tokens", "component", "rate_limiter", "api_key", apiKey.Name, "api_key_id", apiKey.ID, "tokens", tokens, "error", err)
+	}
+}
+
+// GetUsage returns the current consumption of an api key against its limits
+func (r *RateLimiter) GetUsage(apiKey models.APIKey) (models.APIKeyUsage, error) {
+	counters, err := r.APIKeyDB.GetAPIKeyUsage(apiKey.ID, usagePeriods(time.Now()))
+	if err != nil {
+		return models.APIKeyUsage{}, err
+	}
+
+	return models.APIKeyUsage{
+		APIKeyID:          apiKey.ID,
+		Name:              apiKey.Name,
+		Enabled:           apiKey.Enabled,
+		Minute:            counters[models.UsagePeriodMinute],
+		Day:               counters[models.UsagePeriodDay],
+		Month:             counters[models.UsagePeriodMonth],
+		RPMLimit:          apiKey.RPMLimit,
+		TokensPerDayLimit: apiKey.TokensPerDayLimit,
+		MonthlyTokenLimit: apiKey.MonthlyTokenLimit,
+	}, nil
+}
+
+// keyLock returns the lock of an api key
+func (r *RateLimiter) keyLock(apiKeyID int) *sync.Mutex {
+	lock, _ := r.keyLocks.LoadOrStore(apiKeyID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// countRequest persists one request in every period and prunes stale minute rows once per minute
+// Must be called with the key's lock held
+func (r *RateLimiter) countRequest(apiKey models.APIKey, periods map[string]string) {
+	if err := r.APIKeyDB.AddAPIKeyUsage(apiKey.ID, periods, models.UsageCounter{Requests: 1}); err != nil {
+		slog.Error("error recording key request", "component", "rate_limiter", "api_key", apiKey.Name, "api_key_id", apiKey.ID, "error", err)
+	}
+
+	currentMinute := periods[models.UsagePeriodMinute]
+	r.mu.Lock()
+	due := currentMinute != r.lastPrunedMinute
+	if due {
+		r.lastPrunedMinute = currentMinute
+	}
+	r.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := r.APIKeyDB.DeleteAPIKeyUsageBefore(models.UsagePeriodMinute, currentMinute); err != nil {
+		slog.Error("error pruning minute counters", "component", "rate_limiter", "error", err)
+	}
+}
+
+// formatReset formats a reset duration the way OpenAI does in x-ratelimit-reset-* headers (e.g. "1m30s")
+func formatReset(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"air_router/constants"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FailureClass describes how a failed upstream attempt is handled
+type FailureClass string
+
+const (
+	// FailureNone means the attempt succeeded
+	FailureNone FailureClass = ""
+	// FailureClient is the client's own fault: the response is relayed as-is, the account is not penalized
+	FailureClient FailureClass = "client"
+	// FailureAuth means the account credentials were rejected: the account is disabled
+	FailureAuth FailureClass = "auth"
+	// FailureForbidden covers billing and permission refusals, often limited to one model or temporary:
+	// the pair circuit is opened for a long cool-down, then retried elsewhere
+	FailureForbidden FailureClass = "forbidden"
+	// FailureRateLimit opens the pair circuit until the upstream reset time, then retries elsewhere
+	FailureRateLimit FailureClass = "rate_limit"
+	// FailureServer covers 5xx and network errors: counted against the pair circuit, retried elsewhere
+	FailureServer FailureClass = "server"
+	// FailureSlowStream means a stream sent no event within the first chunk timeout: counted against the pair circuit, retried elsewhere
+	FailureSlowStream FailureClass = "slow_stream"
+	// FailureCanceled means the client went away: nothing is penalized and nothing is retried
+	FailureCanceled FailureClass = "canceled"
+	// FailureHedgeLost means a leg was canceled because another leg of the request answered first: nothing is penalized
+	FailureHedgeLost FailureClass = "hedge_lost"
+)
+
+// RetryPolicy configures how many accounts a request may be sent to and for how long
+type RetryPolicy struct {
+	MaxAttempts       int           // All-in-one mode
+	MaxAttemptsDirect int           // Direct model routing
+	Budget            time.Duration // Deadline shared by all attempts until response headers arrive, 0 disables it
+	FirstEventTimeout time.Duration // Wait for the first SSE event of a stream before trying another account, 0 disables it
+}
+
+// LoadRetryPolicy reads the retry policy from the environment
+func LoadRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts:       common.GetEnvIntOrDefault("MAX_ATTEMPTS", 3),
+		MaxAttemptsDirect: common.GetEnvIntOrDefault("MAX_ATTEMPTS_DIRECT", 2),
+		Budget:            time.Duration(common.GetEnvIntOrDefault("UPSTREAM_DEADLINE_MS", 600000)) * time.Millisecond,
+		FirstEventTimeout: time.Duration(common.GetEnvIntOrDefault("STREAM_FIRST_CHUNK_TIMEOUT_MS", 0)) * time.Millisecond,
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.MaxAttemptsDirect < 1 {
+		policy.MaxAttemptsDirect = 1
+	}
+	return policy
+}
+
+// Retry is the retry policy shared by every proxy path
+var Retry = LoadRetryPolicy()
+
+// Attempts returns the number of attempts allowed for a number of candidate accounts
+func (p RetryPolicy) Attempts(maxAttempts, accounts int) int {
+	if accounts < maxAttempts {
+		return accounts
+	}
+	return maxAttempts
+}
+
+// StartBudget starts the deadline budget of a request
+func (p RetryPolicy) StartBudget(c *gin.Context) {
+	if p.Budget > 0 {
+		c.Set(constants.ContextKeyDeadline, time.Now().Add(p.Budget))
+	}
+}
+
+// RemainingBudget returns the time left before the request deadline
+// ok is false when no budget applies to the request
+func RemainingBudget(c *gin.Context) (remaining time.Duration, ok bool) {
+	value, exists := c.Get(constants.ContextKeyDeadline)
+	if !exists {
+		return 0, false
+	}
+	deadline, _ := value.(time.Time)
+	return positive(time.Until(deadline)), true
+}
+
+// BudgetExhausted reports whether the request deadline has passed
+func BudgetExhausted(c *gin.Context) bool {
+	remaining, ok := RemainingBudget(c)
+	return ok && remaining <= 0
+}
+
+// ClassifyResponse classifies an upstream response; a nil response is a network error
+// 404 and 408 are retried elsewhere since they usually mean the account lacks the model or timed out
+func ClassifyResponse(resp *http.Response) FailureClass {
+	if resp == nil {
+		return FailureServer
+	}
+
+	status := resp.StatusCode
+	switch {
+	case status >= 200 && status < 300:
+		return FailureNone
+	case status == http.StatusUnauthorized:
+		return FailureAuth
+	case status == http.StatusPaymentRequired, status == http.StatusForbidden:
+		return FailureForbidden
+	case status == http.StatusTooManyRequests:
+		return FailureRateLimit
+	case status == http.StatusNotFound, status == http.StatusRequestTimeout:
+		return FailureServer
+	case status >= 400 && status < 500:
+		return FailureClient
+	default:
+		return FailureServer
+	}
+}
+
+// Retryable reports whether another account should be tried after a failure
+func (f FailureClass) Retryable() bool {
+	return f == FailureRateLimit || f == FailureServer || f == FailureSlowStream || f == FailureAuth || f == FailureForbidden
+}
+
+// ParseRetryAfter returns the wait announced by an upstream response, or 0 when none is given
+// Supports Retry-After (seconds or HTTP date), OpenAI x-ratelimit-reset-* durations,
+// x-ratelimit-reset (seconds or Unix timestamp) and Anthropic anthropic-ratelimit-*-reset timestamps
+// When several reset headers are present the longest wait wins
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return secondsToDuration(seconds)
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return positive(date.Sub(now))
+		}
+	}
+
+	var wait time.Duration
+	for _, key := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+		if d, err := time.ParseDuration(header.Get(key)); err == nil && d > wait {
+			wait = d
+		}
+	}
+
+	if value := header.Get("x-ratelimit-reset"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			// Large values are absolute Unix timestamps, small ones relative seconds
+			d := secondsToDuration(seconds)
+			if seconds > 1e9 {
+				d = positive(time.Unix(int64(seconds), 0).Sub(now))
+			}
+			if d > wait {
+				wait = d
+			}
+		}
+	}
+
+	for _, key := range []string{"anthropic-ratelimit-requests-reset", "anthropic-ratelimit-tokens-reset", "anthropic-ratelimit-input-tokens-reset", "anthropic-ratelimit-output-tokens-reset"} {
+		if reset, err := time.Parse(time.RFC3339, header.Get(key)); err == nil {
+			if d := positive(reset.Sub(now)); d > wait {
+				wait = d
+			}
+		}
+	}
+
+	return wait
+}
+
+// secondsToDuration converts a non-negative number of seconds to a duration, rounding up
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds*1000)) * time.Millisecond
+}
+
+// positive clamps negative durations to 0
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
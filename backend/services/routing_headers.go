@@ -0,0 +1,52 @@
+package services
+
+import (
+	"strconv"
+
+	"air_router/constants"
+	"air_router/models"
+	"air_router/utils/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoutingHeadersConfig configures the X-Air-* response headers describing how a request was routed
+type RoutingHeadersConfig struct {
+	Enabled     bool
+	HideAccount bool // leave out X-Air-Account for untrusted clients
+}
+
+// LoadRoutingHeadersConfig reads the routing headers configuration from the environment
+func LoadRoutingHeadersConfig() RoutingHeadersConfig {
+	return RoutingHeadersConfig{
+		Enabled:     common.GetEnvOrDefault("ROUTING_HEADERS_ENABLED", "false") == "true",
+		HideAccount: common.GetEnvOrDefault("ROUTING_HEADERS_HIDE_ACCOUNT", "false") == "true",
+	}
+}
+
+// RoutingHeaders is the routing headers configuration shared by every proxy path
+var RoutingHeaders = LoadRoutingHeadersConfig()
+
+// SetRoutingHeaders describes the alias, upstream model, account and attempts of a request in X-Air-* headers
+// It must be called before the response status is written
+func SetRoutingHeaders(c *gin.Context) {
+	if !RoutingHeaders.Enabled {
+		return
+	}
+
+	if alias := c.GetString(constants.ContextKeyAlias); alias != "" {
+		c.Header(constants.AirAliasHeader, alias)
+	}
+	if model := c.GetString(constants.ContextKeyUpstreamModel); model != "" {
+		c.Header(constants.AirUpstreamModelHeader, model)
+	}
+	if value, ok := c.Get(constants.ContextKeyAccount); ok && !RoutingHeaders.HideAccount {
+		c.Header(constants.AirAccountHeader, value.(models.Account).Name)
+	}
+	if value, ok := c.Get(constants.ContextKeyAttempts); ok {
+		c.Header(constants.AirAttemptsHeader, strconv.Itoa(len(value.([]models.RequestAttempt))))
+	}
+	if requestID := c.GetString(constants.ContextKeyRequestID); requestID != "" {
+		c.Header(constants.AirRequestIDHeader, requestID)
+	}
+}
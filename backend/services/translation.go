@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"air_router/models"
+	"air_router/translator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SelectTranslation returns the translation needed to serve a request with an account
+// Returns nil when the request can be passed through unchanged
+func SelectTranslation(provider models.Provider, path string, account models.Account) translator.Translation {
+	endpoint := strings.Trim(path, "/")
+	switch {
+	case provider == models.ProviderClaude && endpoint == "messages" && !account.ClaudeAvailable:
+		return translator.NewMessagesToChat()
+	case account.Protocol == models.ProtocolAnthropic && endpoint == "chat/completions":
+		return translator.NewChatToMessages()
+	case provider == models.ProviderCodex && endpoint == "responses" && !account.ResponsesAvailable:
+		return translator.NewResponsesToChat()
+	}
+	return nil
+}
+
+// TryWithTranslation is TryWithAccountContext converting the request and its outcome with translation when it is not nil
+func (s *ProxyService) TryWithTranslation(ctx context.Context, c *gin.Context, account models.Account, translation translator.Translation, path string, body []byte) (*http.Response, bool, []byte) {
+	if translation == nil {
+		return s.TryWithAccountContext(ctx, c, account, path, body, c.Request.Header)
+	}
+
+	translated, err := translation.Request(body)
+	if err != nil {
+		// The client body cannot be translated, which no other account would change
+		resp := &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}, Body: http.NoBody}
+		resp.Header.Set("Content-Type", "application/json")
+		return resp, false, translation.Error(http.StatusBadRequest, []byte(err.Error()))
+	}
+
+	resp, success, respBody := s.TryWithAccountContext(ctx, c, account, translation.Path(), translated, translation.Headers(c.Request.Header))
+	switch {
+	case success:
+		translation.Response(resp)
+	case resp != nil:
+		respBody = translation.Error(resp.StatusCode, respBody)
+		resp.Header.Del("Content-Length")
+		resp.Header.Set("Content-Type", "application/json")
+	}
+	return resp, success, respBody
+}
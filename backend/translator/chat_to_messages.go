@@ -0,0 +1,529 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultMaxTokens is sent when a chat request has no limit, Anthropic requires max_tokens
+const defaultMaxTokens = 8192
+
+// ChatToMessages serves OpenAI Chat Completions requests with an Anthropic-native account
+type ChatToMessages struct {
+	stream       bool
+	includeUsage bool
+}
+
+// NewChatToMessages creates a new ChatToMessages translation
+func NewChatToMessages() *ChatToMessages {
+	return &ChatToMessages{}
+}
+
+func (t *ChatToMessages) Path() string {
+	return "/messages"
+}
+
+func (t *ChatToMessages) Headers(headers http.Header) http.Header {
+	return withoutHeaders(headers, "OpenAI-Organization", "OpenAI-Project", "OpenAI-Beta")
+}
+
+// Request converts a Chat Completions request into an Anthropic Messages request
+func (t *ChatToMessages) Request(body []byte) ([]byte, error) {
+	var req chatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	t.stream = req.Stream
+	t.includeUsage = req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+
+	messages := anthropicRequest{
+		Model:       req.Model,
+		MaxTokens:   defaultMaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      req.Stream,
+	}
+	if req.MaxCompletionTokens != nil {
+		messages.MaxTokens = *req.MaxCompletionTokens
+	} else if req.MaxTokens != nil {
+		messages.MaxTokens = *req.MaxTokens
+	}
+	messages.StopSequences = chatStopSequences(req.Stop)
+	if req.User != "" {
+		messages.Metadata = &anthropicMetadata{UserID: req.User}
+	}
+	if budget := thinkingBudgetForEffort(req.ReasoningEffort); budget > 0 {
+		messages.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		if messages.MaxTokens <= budget {
+			messages.MaxTokens = budget + defaultMaxTokens
+		}
+		// Extended thinking does not accept sampling parameters
+		messages.Temperature, messages.TopP = nil, nil
+	}
+
+	var system []string
+	var turns []anthropicTurn
+	for _, message := range req.Messages {
+		if message.Role == "system" || message.Role == "developer" {
+			if text := chatContentText(message.Content); text != "" {
+				system = append(system, text)
+			}
+			continue
+		}
+		turns = appendAnthropicTurn(turns, chatMessageToAnthropic(message))
+	}
+	for _, turn := range turns {
+		messages.Messages = append(messages.Messages, anthropicMessage{Role: turn.role, Content: mustMarshal(turn.blocks)})
+	}
+	if len(system) > 0 {
+		messages.System = mustMarshal(strings.Join(system, "\n\n"))
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		messages.Tools = append(messages.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	if len(messages.Tools) > 0 {
+		messages.ToolChoice = chatToolChoiceToAnthropic(req.ToolChoice)
+		if req.ParallelToolCalls != nil && !*req.ParallelToolCalls {
+			if messages.ToolChoice == nil {
+				messages.ToolChoice = &anthropicToolChoice{Type: "auto"}
+			}
+			messages.ToolChoice.DisableParallelToolUse = true
+		}
+	}
+
+	return json.Marshal(messages)
+}
+
+// chatStopSequences reads stop, given either as a string or as strings
+func chatStopSequences(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var stop string
+	if err := json.Unmarshal(raw, &stop); err == nil {
+		if stop == "" {
+			return nil
+		}
+		return []string{stop}
+	}
+	var stops []string
+	json.Unmarshal(raw, &stops)
+	return stops
+}
+
+// thinkingBudgetForEffort maps an OpenAI reasoning effort onto an Anthropic thinking budget, 0 disables thinking
+func thinkingBudgetForEffort(effort string) int {
+	switch effort {
+	case "minimal", "low":
+		return 2048
+	case "medium":
+		return 8192
+	case "high":
+		return 24576
+	default:
+		return 0
+	}
+}
+
+// chatToolChoiceToAnthropic converts tool_choice, given as a string or as a function object
+func chatToolChoiceToAnthropic(raw json.RawMessage) *anthropicToolChoice {
+	if len(raw) == 0 {
+		return nil
+	}
+	var choice string
+	if err := json.Unmarshal(raw, &choice); err == nil {
+		switch choice {
+		case "required":
+			return &anthropicToolChoice{Type: "any"}
+		case "none":
+			return &anthropicToolChoice{Type: "none"}
+		default:
+			return &anthropicToolChoice{Type: "auto"}
+		}
+	}
+	var function struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &function); err == nil && function.Function.Name != "" {
+		return &anthropicToolChoice{Type: "tool", Name: function.Function.Name}
+	}
+	return nil
+}
+
+// chatContentText flattens the text parts of chat content
+func chatContentText(raw json.RawMessage) string {
+	var texts []string
+	for _, part := range parseChatContent(raw) {
+		if part.Type == "text" && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// anthropicTurn is an Anthropic message whose content blocks are still being collected
+type anthropicTurn struct {
+	role   string
+	blocks []anthropicBlock
+}
+
+// chatMessageToAnthropic converts one chat message into an Anthropic turn
+// Tool messages become tool_result blocks of a user turn
+func chatMessageToAnthropic(message chatMessage) anthropicTurn {
+	var blocks []anthropicBlock
+	role := "user"
+
+	switch message.Role {
+	case "assistant":
+		role = "assistant"
+		// Reasoning from earlier turns has no signature and cannot be replayed
+		if text := chatContentText(message.Content); text != "" {
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: text})
+		}
+		for _, call := range message.ToolCalls {
+			blocks = append(blocks, anthropicBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: toolInput(call.Function.Arguments),
+			})
+		}
+	case "tool":
+		blocks = append(blocks, anthropicBlock{
+			Type:      "tool_result",
+			ToolUseID: message.ToolCallID,
+			Content:   mustMarshal(chatContentText(message.Content)),
+		})
+	default:
+		for _, part := range parseChatContent(message.Content) {
+			if block, ok := chatPartToAnthropic(part); ok {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+
+	return anthropicTurn{role: role, blocks: blocks}
+}
+
+// appendAnthropicTurn appends a turn, merging it into the previous one when both have the same role
+// Anthropic requires user and assistant turns to alternate; turns without content are skipped
+func appendAnthropicTurn(turns []anthropicTurn, turn anthropicTurn) []anthropicTurn {
+	if len(turn.blocks) == 0 {
+		return turns
+	}
+	if last := len(turns) - 1; last >= 0 && turns[last].role == turn.role {
+		turns[last].blocks = append(turns[last].blocks, turn.blocks...)
+		return turns
+	}
+	return append(turns, turn)
+}
+
+// chatPartToAnthropic converts a chat content part into an Anthropic content block
+func chatPartToAnthropic(part chatContentPart) (anthropicBlock, bool) {
+	switch part.Type {
+	case "text":
+		if part.Text == "" {
+			return anthropicBlock{}, false
+		}
+		return anthropicBlock{Type: "text", Text: part.Text}, true
+	case "image_url":
+		if part.ImageURL == nil {
+			return anthropicBlock{}, false
+		}
+		return anthropicBlock{Type: "image", Source: anthropicSourceFromURL(part.ImageURL.URL)}, true
+	case "file":
+		if part.File == nil || part.File.FileData == "" {
+			return anthropicBlock{}, false
+		}
+		return anthropicBlock{Type: "document", Source: anthropicSourceFromURL(part.File.FileData)}, true
+	}
+	return anthropicBlock{}, false
+}
+
+// anthropicSourceFromURL converts a data URL into a base64 source and any other URL into a url source
+func anthropicSourceFromURL(url string) *anthropicBlockSource {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if mediaType, data, ok := strings.Cut(rest, ";base64,"); ok {
+			return &anthropicBlockSource{Type: "base64", MediaType: mediaType, Data: data}
+		}
+	}
+	return &anthropicBlockSource{Type: "url", URL: url}
+}
+
+// Response converts an Anthropic message or stream into the Chat Completions format
+func (t *ChatToMessages) Response(resp *http.Response) {
+	if t.stream || isEventStream(resp) {
+		resp.Header.Set("Content-Type", "text/event-stream")
+		transformStream(resp, &messagesToChatStream{includeUsage: t.includeUsage, created: time.Now().Unix()})
+		return
+	}
+	convertJSONBody(resp, messagesResponseToChat)
+}
+
+// messagesResponseToChat converts an Anthropic message into a chat.completion object
+func messagesResponseToChat(body []byte) ([]byte, error) {
+	var message anthropicResponse
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, err
+	}
+	if message.Type != "message" {
+		return nil, fmt.Errorf("unexpected Anthropic response type %q", message.Type)
+	}
+
+	var text, reasoning strings.Builder
+	reply := chatMessage{Role: "assistant"}
+	for _, block := range message.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			reasoning.WriteString(block.Thinking)
+		case "tool_use":
+			arguments := "{}"
+			if len(block.Input) > 0 {
+				arguments = string(block.Input)
+			}
+			reply.ToolCalls = append(reply.ToolCalls, chatToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: chatFunctionCall{Name: block.Name, Arguments: arguments},
+			})
+		}
+	}
+	reply.ReasoningContent = reasoning.String()
+	if text.Len() > 0 || len(reply.ToolCalls) == 0 {
+		reply.Content = mustMarshal(text.String())
+	} else {
+		reply.Content = json.RawMessage("null")
+	}
+
+	finishReason := finishReasonFromStop(message.StopReason)
+	return json.Marshal(chatResponse{
+		ID:      chatCompletionID(message.ID),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   message.Model,
+		Choices: []chatChoice{{Index: 0, Message: &reply, FinishReason: &finishReason}},
+		Usage:   chatUsageFromAnthropic(message.Usage),
+	})
+}
+
+// chatCompletionID gives an Anthropic message ID the chatcmpl- prefix OpenAI clients expect
+func chatCompletionID(id string) string {
+	return "chatcmpl-" + strings.TrimPrefix(id, "msg_")
+}
+
+// finishReasonFromStop maps an Anthropic stop reason onto an OpenAI finish reason
+func finishReasonFromStop(stopReason string) string {
+	switch stopReason {
+	case "max_tokens", "model_context_window_exceeded":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// chatUsageFromAnthropic folds cached input tokens back into OpenAI prompt tokens
+func chatUsageFromAnthropic(usage anthropicUsage) *chatUsage {
+	prompt := usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+	result := &chatUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      prompt + usage.OutputTokens,
+	}
+	if usage.CacheReadInputTokens > 0 {
+		result.PromptTokensDetails = &chatPromptDetails{CachedTokens: usage.CacheReadInputTokens}
+	}
+	return result
+}
+
+// Error converts an Anthropic error into an OpenAI style error
+func (t *ChatToMessages) Error(statusCode int, body []byte) []byte {
+	var upstream errorBody
+	message := strings.TrimSpace(string(body))
+	errorType := ""
+	if err := json.Unmarshal(body, &upstream); err == nil && upstream.Error.Message != "" {
+		message = upstream.Error.Message
+		errorType = upstream.Error.Type
+	}
+	if errorType == "" {
+		errorType = "api_error"
+		if statusCode >= 400 && statusCode < 500 {
+			errorType = "invalid_request_error"
+		}
+	}
+
+	var converted errorBody
+	converted.Error.Type = errorType
+	converted.Error.Message = message
+	data, _ := json.Marshal(converted)
+	return data
+}
+
+// anthropicStreamEvent is the union of the Anthropic stream events used by the translation
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// messagesToChatStream converts Anthropic stream events into chat.completion.chunk events
+type messagesToChatStream struct {
+	includeUsage bool
+	created      int64
+	id           string
+	model        string
+	started      bool
+	finished     bool
+	toolIndexes  map[int]int // Anthropic block index to chat tool call index
+	finishReason string
+	usage        anthropicUsage
+}
+
+func (s *messagesToChatStream) Event(data []byte, out *bytes.Buffer) {
+	if s.finished {
+		return
+	}
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message == nil {
+			return
+		}
+		s.started = true
+		s.id = chatCompletionID(event.Message.ID)
+		s.model = event.Message.Model
+		s.usage = event.Message.Usage
+		s.toolIndexes = map[int]int{}
+		s.writeChunk(chatMessage{Role: "assistant", Content: mustMarshal("")}, nil, out)
+	case "content_block_start":
+		if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+			return
+		}
+		index := len(s.toolIndexes)
+		s.toolIndexes[event.Index] = index
+		s.writeChunk(chatMessage{ToolCalls: []chatToolCall{{
+			Index:    &index,
+			ID:       event.ContentBlock.ID,
+			Type:     "function",
+			Function: chatFunctionCall{Name: event.ContentBlock.Name},
+		}}}, nil, out)
+	case "content_block_delta":
+		if event.Delta == nil {
+			return
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			s.writeChunk(chatMessage{Content: mustMarshal(event.Delta.Text)}, nil, out)
+		case "thinking_delta":
+			s.writeChunk(chatMessage{ReasoningContent: event.Delta.Thinking}, nil, out)
+		case "input_json_delta":
+			index, ok := s.toolIndexes[event.Index]
+			if !ok || event.Delta.PartialJSON == "" {
+				return
+			}
+			s.writeChunk(chatMessage{ToolCalls: []chatToolCall{{
+				Index:    &index,
+				Function: chatFunctionCall{Arguments: event.Delta.PartialJSON},
+			}}}, nil, out)
+		}
+	case "message_delta":
+		if event.Delta != nil && event.Delta.StopReason != "" {
+			s.finishReason = finishReasonFromStop(event.Delta.StopReason)
+		}
+		if event.Usage != nil {
+			s.usage.OutputTokens = event.Usage.OutputTokens
+			if event.Usage.InputTokens > 0 {
+				s.usage.InputTokens = event.Usage.InputTokens
+			}
+		}
+	case "message_stop":
+		s.Finish(out)
+	case "error":
+		// Errors after the stream started are relayed in the OpenAI error envelope
+		writeEvent(out, "", map[string]json.RawMessage{"error": event.Error})
+		s.finished = true
+	}
+}
+
+// writeChunk writes one chat.completion.chunk with a single choice
+func (s *messagesToChatStream) writeChunk(delta chatMessage, finishReason *string, out *bytes.Buffer) {
+	writeEvent(out, "", chatResponse{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []chatChoice{{Index: 0, Delta: &delta, FinishReason: finishReason}},
+	})
+}
+
+// Finish emits the finish reason, the usage and the [DONE] marker
+// Without include_usage the usage rides on the final chunk so it is still accounted
+func (s *messagesToChatStream) Finish(out *bytes.Buffer) {
+	if s.finished || !s.started {
+		return
+	}
+	s.finished = true
+	if s.finishReason == "" {
+		s.finishReason = "stop"
+	}
+
+	final := chatResponse{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []chatChoice{{Index: 0, Delta: &chatMessage{}, FinishReason: &s.finishReason}},
+	}
+	if !s.includeUsage {
+		final.Usage = chatUsageFromAnthropic(s.usage)
+	}
+	writeEvent(out, "", final)
+	if s.includeUsage {
+		writeEvent(out, "", chatResponse{
+			ID:      s.id,
+			Object:  "chat.completion.chunk",
+			Created: s.created,
+			Model:   s.model,
+			Choices: []chatChoice{},
+			Usage:   chatUsageFromAnthropic(s.usage),
+		})
+	}
+	out.WriteString("data: [DONE]\n\n")
+}
@@ -0,0 +1,525 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MessagesToChat serves Anthropic Messages requests with an OpenAI Chat Completions account
+type MessagesToChat struct {
+	stream bool
+}
+
+// NewMessagesToChat creates a new MessagesToChat translation
+func NewMessagesToChat() *MessagesToChat {
+	return &MessagesToChat{}
+}
+
+func (t *MessagesToChat) Path() string {
+	return "/chat/completions"
+}
+
+func (t *MessagesToChat) Headers(headers http.Header) http.Header {
+	return withoutHeaders(headers, "anthropic-version", "anthropic-beta", "X-Api-Key")
+}
+
+// Request converts an Anthropic Messages request into a Chat Completions request
+func (t *MessagesToChat) Request(body []byte) ([]byte, error) {
+	var req anthropicRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	t.stream = req.Stream
+
+	chat := chatRequest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      req.Stream,
+	}
+	if req.MaxTokens > 0 {
+		chat.MaxTokens = &req.MaxTokens
+	}
+	if len(req.StopSequences) > 0 {
+		chat.Stop = mustMarshal(req.StopSequences)
+	}
+	if req.Stream {
+		chat.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+	}
+	if req.Metadata != nil {
+		chat.User = req.Metadata.UserID
+	}
+	if req.Thinking != nil && req.Thinking.Type == "enabled" {
+		chat.ReasoningEffort = reasoningEffortForBudget(req.Thinking.BudgetTokens)
+	}
+
+	if system := anthropicSystemText(req.System); system != "" {
+		chat.Messages = append(chat.Messages, chatMessage{Role: "system", Content: mustMarshal(system)})
+	}
+	for _, message := range req.Messages {
+		chat.Messages = append(chat.Messages, anthropicMessageToChat(message)...)
+	}
+
+	for _, tool := range req.Tools {
+		// Server tools such as web search have no schema and cannot run on a chat account
+		if len(tool.InputSchema) == 0 {
+			continue
+		}
+		chat.Tools = append(chat.Tools, chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+	if req.ToolChoice != nil && len(chat.Tools) > 0 {
+		switch req.ToolChoice.Type {
+		case "auto":
+			chat.ToolChoice = mustMarshal("auto")
+		case "any":
+			chat.ToolChoice = mustMarshal("required")
+		case "none":
+			chat.ToolChoice = mustMarshal("none")
+		case "tool":
+			chat.ToolChoice = mustMarshal(map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": req.ToolChoice.Name},
+			})
+		}
+		if req.ToolChoice.DisableParallelToolUse {
+			parallel := false
+			chat.ParallelToolCalls = &parallel
+		}
+	}
+
+	return json.Marshal(chat)
+}
+
+// reasoningEffortForBudget maps an Anthropic thinking budget onto an OpenAI reasoning effort
+func reasoningEffortForBudget(budget int) string {
+	switch {
+	case budget < 4096:
+		return "low"
+	case budget < 16384:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// anthropicSystemText flattens the system prompt, given as a string or as text blocks
+func anthropicSystemText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var texts []string
+	for _, block := range parseAnthropicBlocks(raw) {
+		if block.Type == "text" && block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// anthropicMessageToChat converts one Anthropic message into one or more chat messages
+// Tool results become tool messages, which must directly follow the assistant message that called them
+func anthropicMessageToChat(message anthropicMessage) []chatMessage {
+	blocks := parseAnthropicBlocks(message.Content)
+
+	if message.Role == "assistant" {
+		var text strings.Builder
+		var toolCalls []chatToolCall
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				text.WriteString(block.Text)
+			case "tool_use":
+				arguments := "{}"
+				if len(block.Input) > 0 {
+					arguments = string(block.Input)
+				}
+				toolCalls = append(toolCalls, chatToolCall{
+					ID:       block.ID,
+					Type:     "function",
+					Function: chatFunctionCall{Name: block.Name, Arguments: arguments},
+				})
+			}
+			// Thinking blocks are not replayed, chat upstreams reject reasoning in the history
+		}
+		chat := chatMessage{Role: "assistant", ToolCalls: toolCalls}
+		if text.Len() > 0 || len(toolCalls) == 0 {
+			chat.Content = mustMarshal(text.String())
+		}
+		return []chatMessage{chat}
+	}
+
+	var result []chatMessage
+	var parts []chatContentPart
+	for _, block := range blocks {
+		switch block.Type {
+		case "tool_result":
+			content, images := toolResultToChat(block)
+			result = append(result, chatMessage{Role: "tool", ToolCallID: block.ToolUseID, Content: mustMarshal(content)})
+			// Tool messages only carry text, images are handed over in the following user message
+			parts = append(parts, images...)
+		default:
+			if part, ok := anthropicBlockToPart(block); ok {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	if len(parts) > 0 {
+		result = append(result, chatMessage{Role: message.Role, Content: chatContent(parts)})
+	} else if len(result) == 0 {
+		result = append(result, chatMessage{Role: message.Role, Content: mustMarshal("")})
+	}
+	return result
+}
+
+// toolResultToChat splits the content of a tool result into its text and its images
+func toolResultToChat(block anthropicBlock) (string, []chatContentPart) {
+	if len(block.Content) == 0 {
+		return "", nil
+	}
+	var texts []string
+	var images []chatContentPart
+	for _, inner := range parseAnthropicBlocks(block.Content) {
+		part, ok := anthropicBlockToPart(inner)
+		if !ok {
+			continue
+		}
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		} else {
+			images = append(images, part)
+		}
+	}
+	return strings.Join(texts, "\n"), images
+}
+
+// anthropicBlockToPart converts a user content block into a chat content part
+func anthropicBlockToPart(block anthropicBlock) (chatContentPart, bool) {
+	switch block.Type {
+	case "text":
+		return chatContentPart{Type: "text", Text: block.Text}, true
+	case "image":
+		if block.Source == nil {
+			return chatContentPart{}, false
+		}
+		url := block.Source.URL
+		if block.Source.Type == "base64" {
+			url = fmt.Sprintf("data:%s;base64,%s", block.Source.MediaType, block.Source.Data)
+		}
+		return chatContentPart{Type: "image_url", ImageURL: &chatImageURL{URL: url}}, true
+	case "document":
+		if block.Source == nil {
+			return chatContentPart{}, false
+		}
+		switch block.Source.Type {
+		case "text":
+			return chatContentPart{Type: "text", Text: block.Source.Data}, true
+		case "base64":
+			return chatContentPart{Type: "file", File: &chatFile{
+				Filename: "document.pdf",
+				FileData: fmt.Sprintf("data:%s;base64,%s", block.Source.MediaType, block.Source.Data),
+			}}, true
+		}
+	}
+	return chatContentPart{}, false
+}
+
+// chatContent encodes parts, using a plain string when the content is a single text
+func chatContent(parts []chatContentPart) json.RawMessage {
+	if len(parts) == 1 && parts[0].Type == "text" {
+		return mustMarshal(parts[0].Text)
+	}
+	return mustMarshal(parts)
+}
+
+// Response converts a Chat Completions response or stream into the Anthropic format
+func (t *MessagesToChat) Response(resp *http.Response) {
+	if t.stream || isEventStream(resp) {
+		resp.Header.Set("Content-Type", "text/event-stream")
+		transformStream(resp, &chatToMessagesStream{})
+		return
+	}
+	convertJSONBody(resp, chatResponseToMessages)
+}
+
+// chatResponseToMessages converts a chat.completion object into an Anthropic message
+func chatResponseToMessages(body []byte) ([]byte, error) {
+	var chat chatResponse
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return nil, err
+	}
+	if len(chat.Choices) == 0 || chat.Choices[0].Message == nil {
+		return nil, fmt.Errorf("chat completion without choices")
+	}
+
+	choice := chat.Choices[0]
+	message := anthropicResponse{
+		ID:      anthropicMessageID(chat.ID),
+		Type:    "message",
+		Role:    "assistant",
+		Model:   chat.Model,
+		Content: []anthropicBlock{},
+	}
+	if choice.Message.ReasoningContent != "" {
+		message.Content = append(message.Content, anthropicBlock{Type: "thinking", Thinking: choice.Message.ReasoningContent})
+	}
+	for _, part := range parseChatContent(choice.Message.Content) {
+		if part.Type == "text" && part.Text != "" {
+			message.Content = append(message.Content, anthropicBlock{Type: "text", Text: part.Text})
+		}
+	}
+	for _, call := range choice.Message.ToolCalls {
+		message.Content = append(message.Content, anthropicBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: toolInput(call.Function.Arguments),
+		})
+	}
+
+	finishReason := ""
+	if choice.FinishReason != nil {
+		finishReason = *choice.FinishReason
+	}
+	message.StopReason = stopReasonFromFinish(finishReason)
+	message.Usage = anthropicUsageFromChat(chat.Usage)
+
+	return json.Marshal(message)
+}
+
+// anthropicMessageID gives a chat completion ID the msg_ prefix Anthropic clients expect
+func anthropicMessageID(id string) string {
+	if strings.HasPrefix(id, "msg_") {
+		return id
+	}
+	return "msg_" + strings.TrimPrefix(id, "chatcmpl-")
+}
+
+// toolInput returns tool call arguments as a JSON object, falling back to an empty one
+func toolInput(arguments string) json.RawMessage {
+	if json.Valid([]byte(arguments)) && strings.HasPrefix(strings.TrimSpace(arguments), "{") {
+		return json.RawMessage(arguments)
+	}
+	return json.RawMessage("{}")
+}
+
+// stopReasonFromFinish maps an OpenAI finish reason onto an Anthropic stop reason
+func stopReasonFromFinish(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// anthropicUsageFromChat splits OpenAI prompt tokens into fresh and cached input tokens
+func anthropicUsageFromChat(usage *chatUsage) anthropicUsage {
+	if usage == nil {
+		return anthropicUsage{}
+	}
+	result := anthropicUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.CacheReadInputTokens = usage.PromptTokensDetails.CachedTokens
+		result.InputTokens -= usage.PromptTokensDetails.CachedTokens
+	}
+	return result
+}
+
+// Error converts an OpenAI style error into an Anthropic error
+func (t *MessagesToChat) Error(statusCode int, body []byte) []byte {
+	var upstream errorBody
+	message := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &upstream); err == nil && upstream.Error.Message != "" {
+		message = upstream.Error.Message
+	}
+
+	var converted errorBody
+	converted.Type = "error"
+	converted.Error.Type = anthropicErrorType(statusCode)
+	converted.Error.Message = message
+	data, _ := json.Marshal(converted)
+	return data
+}
+
+// anthropicErrorType returns the Anthropic error type of an HTTP status
+func anthropicErrorType(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// chatToMessagesStream converts chat.completion.chunk events into Anthropic stream events
+type chatToMessagesStream struct {
+	started    bool
+	finished   bool
+	blockIndex int    // Index of the open content block
+	blockType  string // Type of the open content block, empty when none is open
+	toolIndex  int    // Chat index of the tool call streamed in the open tool_use block
+	stopReason string
+	usage      anthropicUsage
+}
+
+func (s *chatToMessagesStream) Event(data []byte, out *bytes.Buffer) {
+	if s.finished {
+		return
+	}
+	if string(data) == "[DONE]" {
+		s.Finish(out)
+		return
+	}
+
+	var chunk chatResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+	s.start(chunk, out)
+
+	if chunk.Usage != nil {
+		s.usage = anthropicUsageFromChat(chunk.Usage)
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Delta != nil {
+			s.delta(*choice.Delta, out)
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.stopReason = stopReasonFromFinish(*choice.FinishReason)
+		}
+	}
+}
+
+// start emits message_start on the first chunk
+func (s *chatToMessagesStream) start(chunk chatResponse, out *bytes.Buffer) {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.blockIndex = -1
+	writeEvent(out, "message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":            anthropicMessageID(chunk.ID),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         chunk.Model,
+			"content":       []interface{}{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         anthropicUsage{},
+		},
+	})
+}
+
+// delta converts one chat delta into content block events
+func (s *chatToMessagesStream) delta(delta chatMessage, out *bytes.Buffer) {
+	if delta.ReasoningContent != "" {
+		s.openBlock("thinking", map[string]interface{}{"type": "thinking", "thinking": ""}, out)
+		s.writeDelta(map[string]interface{}{"type": "thinking_delta", "thinking": delta.ReasoningContent}, out)
+	}
+
+	for _, part := range parseChatContent(delta.Content) {
+		if part.Type != "text" || part.Text == "" {
+			continue
+		}
+		s.openBlock("text", map[string]interface{}{"type": "text", "text": ""}, out)
+		s.writeDelta(map[string]interface{}{"type": "text_delta", "text": part.Text}, out)
+	}
+
+	for _, call := range delta.ToolCalls {
+		index := 0
+		if call.Index != nil {
+			index = *call.Index
+		}
+		if call.ID != "" || s.blockType != "tool_use" || s.toolIndex != index {
+			s.openBlock("", map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Function.Name, "input": map[string]interface{}{}}, out)
+			s.blockType = "tool_use"
+			s.toolIndex = index
+		}
+		if call.Function.Arguments != "" {
+			s.writeDelta(map[string]interface{}{"type": "input_json_delta", "partial_json": call.Function.Arguments}, out)
+		}
+	}
+}
+
+// openBlock starts a new content block unless one of the same type is already open
+// An empty blockType always starts a new block
+func (s *chatToMessagesStream) openBlock(blockType string, contentBlock map[string]interface{}, out *bytes.Buffer) {
+	if blockType != "" && s.blockType == blockType {
+		return
+	}
+	s.closeBlock(out)
+	s.blockIndex++
+	s.blockType = blockType
+	writeEvent(out, "content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         s.blockIndex,
+		"content_block": contentBlock,
+	})
+}
+
+// closeBlock stops the open content block, if any
+func (s *chatToMessagesStream) closeBlock(out *bytes.Buffer) {
+	if s.blockType == "" {
+		return
+	}
+	writeEvent(out, "content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": s.blockIndex})
+	s.blockType = ""
+}
+
+func (s *chatToMessagesStream) writeDelta(delta map[string]interface{}, out *bytes.Buffer) {
+	writeEvent(out, "content_block_delta", map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": s.blockIndex,
+		"delta": delta,
+	})
+}
+
+// Finish closes the open block and emits message_delta and message_stop
+func (s *chatToMessagesStream) Finish(out *bytes.Buffer) {
+	if s.finished || !s.started {
+		return
+	}
+	s.finished = true
+	s.closeBlock(out)
+	if s.stopReason == "" {
+		s.stopReason = "end_turn"
+	}
+	writeEvent(out, "message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": s.stopReason, "stop_sequence": nil},
+		"usage": s.usage,
+	})
+	writeEvent(out, "message_stop", map[string]interface{}{"type": "message_stop"})
+}
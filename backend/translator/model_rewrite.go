@@ -0,0 +1,144 @@
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// modelFieldContainers are the objects of a response or event that may carry the model, besides the top level:
+// Anthropic's message_start carries it in message, Responses events in response
+var modelFieldContainers = []string{"message", "response"}
+
+// RewriteModel replaces the model named by a successful response, a JSON body or every SSE event, with model
+// Used to report the alias a client asked for instead of the upstream model that served it
+func RewriteModel(resp *http.Response, model string) {
+	rewrite := func(data []byte) ([]byte, error) {
+		return rewriteModelField(data, model)
+	}
+	switch {
+	case isEventStream(resp):
+		resp.Body = &lineTransformer{body: resp.Body, reader: bufio.NewReader(resp.Body), transform: rewrite}
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+	case strings.Contains(resp.Header.Get("Content-Type"), "json"):
+		reader := bufio.NewReader(resp.Body)
+		resp.Body = &peekedBody{Reader: reader, Closer: resp.Body}
+		// Only a single object is buffered and rewritten; arrays, such as Gemini's streamGenerateContent
+		// without alt=sse, are relayed unchanged as they arrive
+		if !startsWithObject(reader) {
+			return
+		}
+		contentType := resp.Header.Get("Content-Type")
+		convertJSONBody(resp, rewrite)
+		resp.Header.Set("Content-Type", contentType)
+	}
+}
+
+// startsWithObject reports whether the first non-whitespace byte of a JSON body opens an object
+func startsWithObject(reader *bufio.Reader) bool {
+	for n := 1; ; n++ {
+		peeked, _ := reader.Peek(n)
+		if len(peeked) < n {
+			return false
+		}
+		switch peeked[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return peeked[n-1] == '{'
+	}
+}
+
+// peekedBody reads a body through the reader that peeked at it
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// rewriteModelField sets model, and Gemini's modelVersion, wherever a JSON object already names one
+func rewriteModelField(data []byte, model string) ([]byte, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	value := mustMarshal(model)
+
+	changed := false
+	for _, field := range []string{"model", "modelVersion"} {
+		if _, ok := object[field]; ok {
+			object[field] = value
+			changed = true
+		}
+	}
+	for _, container := range modelFieldContainers {
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(object[container], &nested) != nil {
+			continue
+		}
+		if _, ok := nested["model"]; ok {
+			nested["model"] = value
+			object[container] = marshalRaw(nested)
+			changed = true
+		}
+	}
+	if !changed {
+		return data, nil
+	}
+	return marshalRaw(object), nil
+}
+
+// marshalRaw encodes a JSON object without escaping HTML characters, so untouched content keeps its bytes
+func marshalRaw(object map[string]json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(object)
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+// lineTransformer rewrites the data lines of an SSE body while it is being streamed
+// Unlike sseTransformer every other line, event names included, is relayed unchanged
+type lineTransformer struct {
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	transform func([]byte) ([]byte, error)
+	out       bytes.Buffer
+	err       error
+	done      bool
+}
+
+func (t *lineTransformer) Read(p []byte) (int, error) {
+	for t.out.Len() == 0 && !t.done {
+		line, err := t.reader.ReadBytes('\n')
+		if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			payload := bytes.TrimSpace(data)
+			if converted, convertErr := t.transform(payload); convertErr == nil {
+				ending := line[len(bytes.TrimRight(line, "\r\n")):]
+				line = append(append([]byte("data: "), converted...), ending...)
+			}
+		}
+		t.out.Write(line)
+		if err != nil {
+			t.done = true
+			if err != io.EOF {
+				t.err = err
+			}
+		}
+	}
+
+	if t.out.Len() > 0 {
+		return t.out.Read(p)
+	}
+	if t.err != nil {
+		return 0, t.err
+	}
+	return 0, io.EOF
+}
+
+func (t *lineTransformer) Close() error {
+	return t.body.Close()
+}
@@ -0,0 +1,69 @@
+package translator
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func jsonResponse(body io.ReadCloser) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       body,
+	}
+}
+
+func TestRewriteModelRewritesJSONObject(t *testing.T) {
+	resp := jsonResponse(io.NopCloser(strings.NewReader(`{"id":"1","model":"gpt-4o-2024-08-06","modelVersion":"v"}`)))
+	RewriteModel(resp, "fast")
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":"1","model":"fast","modelVersion":"fast"}`; string(body) != want {
+		t.Fatalf("body = %s, want %s", body, want)
+	}
+}
+
+func TestRewriteModelStreamsJSONArrayUnchanged(t *testing.T) {
+	reader, writer := io.Pipe()
+	first := "\n[{\"modelVersion\":\"gemini-2.5-flash\"}"
+	rest := ",{\"modelVersion\":\"gemini-2.5-flash\"}]"
+	go writer.Write([]byte(first))
+
+	resp := jsonResponse(reader)
+	rewritten := make(chan struct{})
+	go func() {
+		RewriteModel(resp, "fast")
+		close(rewritten)
+	}()
+	select {
+	case <-rewritten:
+	case <-time.After(time.Second):
+		t.Fatal("RewriteModel waited for the whole array body")
+	}
+
+	chunk := make([]byte, len(first))
+	if _, err := io.ReadFull(resp.Body, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if string(chunk) != first {
+		t.Fatalf("first chunk = %q, want %q", chunk, first)
+	}
+
+	go func() {
+		writer.Write([]byte(rest))
+		writer.Close()
+	}()
+	remaining, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(remaining) != rest {
+		t.Fatalf("rest = %q, want %q", remaining, rest)
+	}
+}
@@ -0,0 +1,612 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errStatefulResponse is returned for requests continuing a stored response, which chat accounts cannot look up
+var errStatefulResponse = errors.New("previous_response_id is not supported by this account, send the full conversation in input")
+
+// ResponsesToChat serves OpenAI Responses API requests with an OpenAI Chat Completions account
+type ResponsesToChat struct {
+	stream bool
+}
+
+// NewResponsesToChat creates a new ResponsesToChat translation
+func NewResponsesToChat() *ResponsesToChat {
+	return &ResponsesToChat{}
+}
+
+func (t *ResponsesToChat) Path() string {
+	return "/chat/completions"
+}
+
+func (t *ResponsesToChat) Headers(headers http.Header) http.Header {
+	return withoutHeaders(headers, "OpenAI-Beta")
+}
+
+// Request converts a Responses API request into a Chat Completions request
+func (t *ResponsesToChat) Request(body []byte) ([]byte, error) {
+	var req responsesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if req.PreviousResponseID != "" {
+		return nil, errStatefulResponse
+	}
+	t.stream = req.Stream
+
+	chat := chatRequest{
+		Model:             req.Model,
+		MaxTokens:         req.MaxOutputTokens,
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		Stream:            req.Stream,
+		ParallelToolCalls: req.ParallelToolCalls,
+		User:              req.User,
+	}
+	if req.Stream {
+		chat.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+	}
+	if req.Reasoning != nil {
+		chat.ReasoningEffort = req.Reasoning.Effort
+	}
+	if req.Text != nil && req.Text.Format != nil {
+		chat.ResponseFormat = chatResponseFormat(req.Text.Format)
+	}
+
+	if req.Instructions != "" {
+		chat.Messages = append(chat.Messages, chatMessage{Role: "system", Content: mustMarshal(req.Instructions)})
+	}
+	chat.Messages = append(chat.Messages, responsesInputToChat(req.Input)...)
+
+	for _, tool := range req.Tools {
+		// Built-in tools such as web_search run on OpenAI's side and cannot be forwarded
+		if tool.Type != "function" {
+			continue
+		}
+		chat.Tools = append(chat.Tools, chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	if len(chat.Tools) > 0 {
+		chat.ToolChoice = responsesToolChoiceToChat(req.ToolChoice)
+	} else {
+		chat.ParallelToolCalls = nil
+	}
+
+	return json.Marshal(chat)
+}
+
+// chatResponseFormat converts a Responses text format into a Chat Completions response_format
+func chatResponseFormat(format *responsesTextFormat) json.RawMessage {
+	switch format.Type {
+	case "json_object":
+		return mustMarshal(map[string]string{"type": "json_object"})
+	case "json_schema":
+		return mustMarshal(map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   format.Name,
+				"schema": format.Schema,
+				"strict": format.Strict,
+			},
+		})
+	}
+	return nil
+}
+
+// responsesToolChoiceToChat converts tool_choice, given as a string or as {"type": "function", "name": ...}
+func responsesToolChoiceToChat(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var choice string
+	if err := json.Unmarshal(raw, &choice); err == nil {
+		return mustMarshal(choice)
+	}
+	var function struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &function); err == nil && function.Type == "function" && function.Name != "" {
+		return mustMarshal(map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": function.Name},
+		})
+	}
+	return nil
+}
+
+// responsesInputToChat converts the input, given as a string or as items, into chat messages
+// Consecutive function calls are grouped into one assistant message followed by their tool messages
+func responsesInputToChat(raw json.RawMessage) []chatMessage {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []chatMessage{{Role: "user", Content: mustMarshal(text)}}
+	}
+	var items []responsesItem
+	json.Unmarshal(raw, &items)
+
+	var messages []chatMessage
+	for _, item := range items {
+		switch item.Type {
+		case "function_call":
+			call := chatToolCall{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: chatFunctionCall{Name: item.Name, Arguments: item.Arguments},
+			}
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "assistant" {
+				messages[last].ToolCalls = append(messages[last].ToolCalls, call)
+				continue
+			}
+			messages = append(messages, chatMessage{Role: "assistant", ToolCalls: []chatToolCall{call}})
+		case "function_call_output":
+			messages = append(messages, chatMessage{Role: "tool", ToolCallID: item.CallID, Content: mustMarshal(responsesOutputText(item.Output))})
+		case "message", "":
+			if message, ok := responsesMessageToChat(item); ok {
+				messages = append(messages, message)
+			}
+		}
+		// Reasoning items are encrypted or summarized and cannot be replayed to a chat account
+	}
+	return messages
+}
+
+// responsesOutputText returns the output of a function call, given as a string or as parts
+func responsesOutputText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var texts []string
+	for _, part := range parseResponsesContent(raw) {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// parseResponsesContent parses message content given either as a string or as parts
+func parseResponsesContent(raw json.RawMessage) []responsesContentPart {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []responsesContentPart{{Type: "input_text", Text: text}}
+	}
+	var parts []responsesContentPart
+	json.Unmarshal(raw, &parts)
+	return parts
+}
+
+// responsesMessageToChat converts a message item into a chat message
+func responsesMessageToChat(item responsesItem) (chatMessage, bool) {
+	role := item.Role
+	if role == "developer" {
+		role = "system"
+	}
+	if role == "" {
+		return chatMessage{}, false
+	}
+
+	var parts []chatContentPart
+	for _, part := range parseResponsesContent(item.Content) {
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			parts = append(parts, chatContentPart{Type: "text", Text: part.Text})
+		case "input_image":
+			if part.ImageURL != "" {
+				parts = append(parts, chatContentPart{Type: "image_url", ImageURL: &chatImageURL{URL: part.ImageURL}})
+			}
+		case "input_file":
+			if part.FileData != "" {
+				parts = append(parts, chatContentPart{Type: "file", File: &chatFile{Filename: part.Filename, FileData: part.FileData}})
+			}
+		}
+	}
+
+	// Only user messages may carry images and files
+	if role != "user" {
+		var texts []string
+		for _, part := range parts {
+			if part.Type == "text" {
+				texts = append(texts, part.Text)
+			}
+		}
+		return chatMessage{Role: role, Content: mustMarshal(strings.Join(texts, "\n"))}, true
+	}
+	if len(parts) == 0 {
+		return chatMessage{}, false
+	}
+	return chatMessage{Role: role, Content: chatContent(parts)}, true
+}
+
+// Response converts a Chat Completions response or stream into the Responses API format
+func (t *ResponsesToChat) Response(resp *http.Response) {
+	if t.stream || isEventStream(resp) {
+		resp.Header.Set("Content-Type", "text/event-stream")
+		transformStream(resp, &chatToResponsesStream{created: time.Now().Unix()})
+		return
+	}
+	convertJSONBody(resp, chatResponseToResponses)
+}
+
+// chatResponseToResponses converts a chat.completion object into a response object
+func chatResponseToResponses(body []byte) ([]byte, error) {
+	var chat chatResponse
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return nil, err
+	}
+	if len(chat.Choices) == 0 || chat.Choices[0].Message == nil {
+		return nil, errors.New("chat completion without choices")
+	}
+
+	choice := chat.Choices[0]
+	baseID := strings.TrimPrefix(chat.ID, "chatcmpl-")
+	output := []interface{}{}
+	if choice.Message.ReasoningContent != "" {
+		output = append(output, responsesReasoningItem("rs_"+baseID, choice.Message.ReasoningContent))
+	}
+	if text := chatContentText(choice.Message.Content); text != "" {
+		output = append(output, responsesMessageItem("msg_"+baseID, text, "completed"))
+	}
+	for _, call := range choice.Message.ToolCalls {
+		output = append(output, responsesFunctionCallItem("fc_"+call.ID, call.ID, call.Function.Name, call.Function.Arguments, "completed"))
+	}
+
+	finishReason := "stop"
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		finishReason = *choice.FinishReason
+	}
+	return json.Marshal(responsesObject(responseID(chat.ID), chat.Model, time.Now().Unix(), finishReason, output, chat.Usage))
+}
+
+// responseID gives a chat completion ID the resp_ prefix Responses clients expect
+func responseID(id string) string {
+	return "resp_" + strings.TrimPrefix(id, "chatcmpl-")
+}
+
+// responsesObject builds a response object; an empty finish reason means the response is still in progress
+func responsesObject(id string, model string, created int64, finishReason string, output []interface{}, usage *chatUsage) map[string]interface{} {
+	status := "completed"
+	var incomplete interface{}
+	switch finishReason {
+	case "":
+		status = "in_progress"
+	case "length":
+		status = "incomplete"
+		incomplete = map[string]string{"reason": "max_output_tokens"}
+	case "content_filter":
+		status = "incomplete"
+		incomplete = map[string]string{"reason": "content_filter"}
+	}
+
+	response := map[string]interface{}{
+		"id":                 id,
+		"object":             "response",
+		"created_at":         created,
+		"status":             status,
+		"model":              model,
+		"output":             output,
+		"incomplete_details": incomplete,
+		"error":              nil,
+		"usage":              nil,
+	}
+	if usage != nil {
+		response["usage"] = responsesUsageFromChat(usage)
+	}
+	return response
+}
+
+// responsesUsageFromChat converts chat usage into Responses usage
+func responsesUsageFromChat(usage *chatUsage) responsesUsage {
+	var result responsesUsage
+	result.InputTokens = usage.PromptTokens
+	result.OutputTokens = usage.CompletionTokens
+	result.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	if usage.PromptTokensDetails != nil {
+		result.InputTokensDetails.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		result.OutputTokensDetails.ReasoningTokens = usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return result
+}
+
+// responsesMessageItem builds an assistant message output item
+func responsesMessageItem(id string, text string, status string) map[string]interface{} {
+	content := []interface{}{}
+	if status == "completed" {
+		content = append(content, responsesTextPart(text))
+	}
+	return map[string]interface{}{
+		"type":    "message",
+		"id":      id,
+		"status":  status,
+		"role":    "assistant",
+		"content": content,
+	}
+}
+
+// responsesTextPart builds an output_text content part
+func responsesTextPart(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "output_text", "text": text, "annotations": []interface{}{}}
+}
+
+// responsesFunctionCallItem builds a function_call output item
+func responsesFunctionCallItem(id string, callID string, name string, arguments string, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "function_call",
+		"id":        id,
+		"call_id":   callID,
+		"name":      name,
+		"arguments": arguments,
+		"status":    status,
+	}
+}
+
+// responsesReasoningItem builds a reasoning output item with its summary; an empty summary leaves it open
+func responsesReasoningItem(id string, summary string) map[string]interface{} {
+	parts := []interface{}{}
+	if summary != "" {
+		parts = append(parts, map[string]string{"type": "summary_text", "text": summary})
+	}
+	return map[string]interface{}{"type": "reasoning", "id": id, "summary": parts}
+}
+
+// Error converts an upstream error into the OpenAI error envelope shared by both APIs
+func (t *ResponsesToChat) Error(statusCode int, body []byte) []byte {
+	var upstream errorBody
+	if err := json.Unmarshal(body, &upstream); err == nil && upstream.Error.Message != "" {
+		return body
+	}
+
+	var converted errorBody
+	converted.Error.Type = "api_error"
+	if statusCode >= 400 && statusCode < 500 {
+		converted.Error.Type = "invalid_request_error"
+	}
+	converted.Error.Message = strings.TrimSpace(string(body))
+	data, _ := json.Marshal(converted)
+	return data
+}
+
+// responsesStreamItem is an output item being streamed
+type responsesStreamItem struct {
+	kind        string // reasoning, message or function_call
+	id          string
+	outputIndex int
+	callID      string
+	name        string
+	text        strings.Builder // Summary, text or arguments
+}
+
+// chatToResponsesStream converts chat.completion.chunk events into Responses API stream events
+type chatToResponsesStream struct {
+	created      int64
+	id           string
+	baseID       string
+	model        string
+	sequence     int
+	started      bool
+	finished     bool
+	open         []*responsesStreamItem       // Items not done yet, in output order
+	tools        map[int]*responsesStreamItem // Open function calls by chat tool call index
+	output       []interface{}
+	finishReason string
+	usage        *chatUsage
+}
+
+func (s *chatToResponsesStream) Event(data []byte, out *bytes.Buffer) {
+	if s.finished {
+		return
+	}
+	if string(data) == "[DONE]" {
+		s.Finish(out)
+		return
+	}
+
+	var chunk chatResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+	if chunk.ID == "" && len(chunk.Choices) == 0 && chunk.Usage == nil {
+		s.streamError(data, out)
+		return
+	}
+	s.start(chunk, out)
+
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Delta != nil {
+			s.delta(*choice.Delta, out)
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.finishReason = *choice.FinishReason
+		}
+	}
+}
+
+// streamError relays an error object sent in place of a chunk and ends the stream
+func (s *chatToResponsesStream) streamError(data []byte, out *bytes.Buffer) {
+	var upstream errorBody
+	if err := json.Unmarshal(data, &upstream); err != nil || upstream.Error.Message == "" {
+		return
+	}
+	s.emit(out, "error", map[string]interface{}{"code": upstream.Error.Type, "message": upstream.Error.Message})
+	s.finished = true
+}
+
+// emit writes one stream event, numbering it
+func (s *chatToResponsesStream) emit(out *bytes.Buffer, eventType string, fields map[string]interface{}) {
+	fields["type"] = eventType
+	fields["sequence_number"] = s.sequence
+	s.sequence++
+	writeEvent(out, eventType, fields)
+}
+
+// start emits response.created and response.in_progress on the first chunk
+func (s *chatToResponsesStream) start(chunk chatResponse, out *bytes.Buffer) {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.id = responseID(chunk.ID)
+	s.baseID = strings.TrimPrefix(chunk.ID, "chatcmpl-")
+	s.model = chunk.Model
+	s.tools = map[int]*responsesStreamItem{}
+	s.output = []interface{}{}
+
+	response := responsesObject(s.id, s.model, s.created, "", []interface{}{}, nil)
+	s.emit(out, "response.created", map[string]interface{}{"response": response})
+	s.emit(out, "response.in_progress", map[string]interface{}{"response": response})
+}
+
+// delta converts one chat delta into output item events
+func (s *chatToResponsesStream) delta(delta chatMessage, out *bytes.Buffer) {
+	if delta.ReasoningContent != "" {
+		item := s.openItem("reasoning", out)
+		item.text.WriteString(delta.ReasoningContent)
+		s.emit(out, "response.reasoning_summary_text.delta", map[string]interface{}{
+			"item_id": item.id, "output_index": item.outputIndex, "summary_index": 0, "delta": delta.ReasoningContent,
+		})
+	}
+
+	if text := chatContentText(delta.Content); text != "" {
+		item := s.openItem("message", out)
+		item.text.WriteString(text)
+		s.emit(out, "response.output_text.delta", map[string]interface{}{
+			"item_id": item.id, "output_index": item.outputIndex, "content_index": 0, "delta": text,
+		})
+	}
+
+	for _, call := range delta.ToolCalls {
+		index := 0
+		if call.Index != nil {
+			index = *call.Index
+		}
+		item, ok := s.tools[index]
+		if !ok {
+			item = s.openFunctionCall(call, out)
+			s.tools[index] = item
+		}
+		if call.Function.Arguments != "" {
+			item.text.WriteString(call.Function.Arguments)
+			s.emit(out, "response.function_call_arguments.delta", map[string]interface{}{
+				"item_id": item.id, "output_index": item.outputIndex, "delta": call.Function.Arguments,
+			})
+		}
+	}
+}
+
+// openItem returns the open reasoning or message item, closing the items of other kinds first
+func (s *chatToResponsesStream) openItem(kind string, out *bytes.Buffer) *responsesStreamItem {
+	if last := len(s.open) - 1; last >= 0 && s.open[last].kind == kind {
+		return s.open[last]
+	}
+	s.closeItems(out)
+
+	item := &responsesStreamItem{kind: kind, outputIndex: len(s.output)}
+	s.open = append(s.open, item)
+	switch kind {
+	case "reasoning":
+		item.id = "rs_" + s.baseID
+		s.emit(out, "response.output_item.added", map[string]interface{}{"output_index": item.outputIndex, "item": responsesReasoningItem(item.id, "")})
+		s.emit(out, "response.reasoning_summary_part.added", map[string]interface{}{
+			"item_id": item.id, "output_index": item.outputIndex, "summary_index": 0, "part": map[string]string{"type": "summary_text", "text": ""},
+		})
+	case "message":
+		item.id = "msg_" + s.baseID
+		s.emit(out, "response.output_item.added", map[string]interface{}{"output_index": item.outputIndex, "item": responsesMessageItem(item.id, "", "in_progress")})
+		s.emit(out, "response.content_part.added", map[string]interface{}{
+			"item_id": item.id, "output_index": item.outputIndex, "content_index": 0, "part": responsesTextPart(""),
+		})
+	}
+	// Reserve the output slot so that later items get the next index
+	s.output = append(s.output, nil)
+	return item
+}
+
+// openFunctionCall starts a function_call item; parallel calls stay open together until the turn ends
+func (s *chatToResponsesStream) openFunctionCall(call chatToolCall, out *bytes.Buffer) *responsesStreamItem {
+	if last := len(s.open) - 1; last >= 0 && s.open[last].kind != "function_call" {
+		s.closeItems(out)
+	}
+	item := &responsesStreamItem{kind: "function_call", id: "fc_" + call.ID, outputIndex: len(s.output), callID: call.ID, name: call.Function.Name}
+	s.open = append(s.open, item)
+	s.output = append(s.output, nil)
+	s.emit(out, "response.output_item.added", map[string]interface{}{
+		"output_index": item.outputIndex,
+		"item":         responsesFunctionCallItem(item.id, item.callID, item.name, "", "in_progress"),
+	})
+	return item
+}
+
+// closeItems emits the done events of every open item and records them in the output
+func (s *chatToResponsesStream) closeItems(out *bytes.Buffer) {
+	sort.Slice(s.open, func(i, j int) bool { return s.open[i].outputIndex < s.open[j].outputIndex })
+	for _, item := range s.open {
+		text := item.text.String()
+		var done map[string]interface{}
+		switch item.kind {
+		case "reasoning":
+			s.emit(out, "response.reasoning_summary_text.done", map[string]interface{}{
+				"item_id": item.id, "output_index": item.outputIndex, "summary_index": 0, "text": text,
+			})
+			s.emit(out, "response.reasoning_summary_part.done", map[string]interface{}{
+				"item_id": item.id, "output_index": item.outputIndex, "summary_index": 0, "part": map[string]string{"type": "summary_text", "text": text},
+			})
+			done = responsesReasoningItem(item.id, text)
+		case "message":
+			s.emit(out, "response.output_text.done", map[string]interface{}{
+				"item_id": item.id, "output_index": item.outputIndex, "content_index": 0, "text": text,
+			})
+			s.emit(out, "response.content_part.done", map[string]interface{}{
+				"item_id": item.id, "output_index": item.outputIndex, "content_index": 0, "part": responsesTextPart(text),
+			})
+			done = responsesMessageItem(item.id, text, "completed")
+		case "function_call":
+			s.emit(out, "response.function_call_arguments.done", map[string]interface{}{
+				"item_id": item.id, "output_index": item.outputIndex, "arguments": text,
+			})
+			done = responsesFunctionCallItem(item.id, item.callID, item.name, text, "completed")
+		}
+		s.emit(out, "response.output_item.done", map[string]interface{}{"output_index": item.outputIndex, "item": done})
+		s.output[item.outputIndex] = done
+	}
+	s.open = nil
+	s.tools = map[int]*responsesStreamItem{}
+}
+
+// Finish closes the open items and emits response.completed, or response.incomplete when the output was cut
+func (s *chatToResponsesStream) Finish(out *bytes.Buffer) {
+	if s.finished || !s.started {
+		return
+	}
+	s.finished = true
+	s.closeItems(out)
+	if s.finishReason == "" {
+		s.finishReason = "stop"
+	}
+
+	response := responsesObject(s.id, s.model, s.created, s.finishReason, s.output, s.usage)
+	eventType := "response.completed"
+	if response["status"] == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	s.emit(out, eventType, map[string]interface{}{"response": response})
+}
@@ -0,0 +1,134 @@
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Translation converts a request between the client protocol and the protocol spoken by an upstream account
+// A Translation is created per upstream attempt and may keep state between Request and Response
+type Translation interface {
+	// Path returns the upstream path of the translated request
+	Path() string
+	// Request converts the client request body to the upstream protocol
+	Request(body []byte) ([]byte, error)
+	// Headers returns the client headers to forward, without the ones that only apply to the client protocol
+	Headers(headers http.Header) http.Header
+	// Response converts a successful upstream response back to the client protocol in place
+	Response(resp *http.Response)
+	// Error converts an upstream error body to the client protocol
+	Error(statusCode int, body []byte) []byte
+}
+
+// replaceBody swaps a fully converted body into a response
+func replaceBody(resp *http.Response, body []byte) {
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Content-Type", "application/json")
+}
+
+// convertJSONBody converts a buffered JSON response with convert, leaving the body untouched when it fails
+func convertJSONBody(resp *http.Response, convert func([]byte) ([]byte, error)) {
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		if converted, convertErr := convert(body); convertErr == nil {
+			body = converted
+		}
+	}
+	replaceBody(resp, body)
+}
+
+// isEventStream reports whether a response is a server-sent event stream
+func isEventStream(resp *http.Response) bool {
+	return bytes.HasPrefix([]byte(resp.Header.Get("Content-Type")), []byte("text/event-stream"))
+}
+
+// withoutHeaders returns a copy of headers without the given keys
+func withoutHeaders(headers http.Header, keys ...string) http.Header {
+	result := headers.Clone()
+	for _, key := range keys {
+		result.Del(key)
+	}
+	result.Del("Content-Length")
+	return result
+}
+
+// eventHandler converts SSE events of one protocol into the other
+type eventHandler interface {
+	// Event handles the payload of one upstream data line, writing converted events to out
+	Event(data []byte, out *bytes.Buffer)
+	// Finish writes the trailing events once the upstream stream ended
+	Finish(out *bytes.Buffer)
+}
+
+// sseTransformer rewrites an upstream SSE body event by event while it is being streamed
+type sseTransformer struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	handler eventHandler
+	out     bytes.Buffer
+	err     error
+	done    bool
+}
+
+// transformStream replaces the body of a streaming response with its converted events
+func transformStream(resp *http.Response, handler eventHandler) {
+	resp.Body = &sseTransformer{
+		body:    resp.Body,
+		reader:  bufio.NewReader(resp.Body),
+		handler: handler,
+	}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+}
+
+func (t *sseTransformer) Read(p []byte) (int, error) {
+	for t.out.Len() == 0 && !t.done {
+		line, err := t.reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			t.handler.Event(bytes.TrimSpace(data), &t.out)
+		}
+		if err != nil {
+			t.done = true
+			if err != io.EOF {
+				t.err = err
+			}
+			t.handler.Finish(&t.out)
+		}
+	}
+
+	if t.out.Len() > 0 {
+		return t.out.Read(p)
+	}
+	if t.err != nil {
+		return 0, t.err
+	}
+	return 0, io.EOF
+}
+
+func (t *sseTransformer) Close() error {
+	return t.body.Close()
+}
+
+// writeEvent writes one named SSE event with a JSON payload
+func writeEvent(out *bytes.Buffer, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	if event != "" {
+		out.WriteString("event: ")
+		out.WriteString(event)
+		out.WriteString("\n")
+	}
+	out.WriteString("data: ")
+	out.Write(data)
+	out.WriteString("\n\n")
+}
@@ -60,22 +60,44 @@ const (
 	ErrTypeInvalidProvider = "invalid_provider_error"
 	ErrTypeAccountNotFound = "account_not_found_error"
 	ErrTypeModelNotFound   = "model_not_found_error"
+	ErrTypeRateLimit       = "rate_limit_error"
+	ErrTypeInvalidStrategy = "invalid_strategy_error"
+	ErrTypeInvalidProtocol = "invalid_protocol_error"
 )
 
 // Common error messages
 const (
-	ErrMsgInvalidID          = "Invalid ID parameter"
-	ErrMsgAccountNotFound    = "Account not found"
-	ErrMsgModelNotFound      = "Model not found"
-	ErrMsgFailedToReadBody   = "Failed to read request body"
-	ErrMsgFailedToParseBody  = "Failed to parse request body"
-	ErrMsgModelMissing       = "model '' is missing"
-	ErrMsgInvalidProvider    = "Invalid provider"
-	ErrMsgFailedToDelete     = "Failed to delete resource"
-	ErrMsgFailedToToggle     = "Failed to toggle resource"
-	ErrMsgFailedToUpdate     = "Failed to retrieve updated resource"
-	ErrMsgAllAttemptsFailed  = "All account attempts failed"
-	ErrMsgNoAccountsFound    = "No accounts found for model '%s'"
-	ErrMsgNoModelsFound      = "No actual models found for model '%s'"
-	ErrMsgFailedToUpdateBody = "Failed to update request body"
+	ErrMsgInvalidID              = "Invalid ID parameter"
+	ErrMsgAccountNotFound        = "Account not found"
+	ErrMsgModelNotFound          = "Model not found"
+	ErrMsgFailedToReadBody       = "Failed to read request body"
+	ErrMsgFailedToParseBody      = "Failed to parse request body"
+	ErrMsgModelMissing           = "model '' is missing"
+	ErrMsgInvalidProvider        = "Invalid provider"
+	ErrMsgInvalidStrategy        = "Invalid routing strategy"
+	ErrMsgInvalidProtocol        = "Invalid account protocol"
+	ErrMsgFailedToDelete         = "Failed to delete resource"
+	ErrMsgFailedToToggle         = "Failed to toggle resource"
+	ErrMsgFailedToUpdate         = "Failed to retrieve updated resource"
+	ErrMsgAllAttemptsFailed      = "All account attempts failed"
+	ErrMsgAllAccountsUnavailable = "All accounts for model '%s' are temporarily unavailable"
+	ErrMsgDeadlineExceeded       = "Upstream accounts did not respond before the request deadline"
+	ErrMsgNoAccountsFound        = "No accounts found for model '%s'"
+	ErrMsgNoAccountsForPath      = "No accounts available for path '%s'"
+	ErrMsgNoModelsFound          = "No actual models found for model '%s'"
+	ErrMsgUnsupportedGeminiPath  = "Unsupported Gemini path '%s', expected models/{model}:{method}"
+	ErrMsgFailedToUpdateBody     = "Failed to update request body"
+	ErrMsgAPIKeyMissing          = "Missing API key"
+	ErrMsgInvalidAPIKey          = "Invalid API key"
+	ErrMsgAPIKeyCheckFailed      = "Failed to verify API key"
+	ErrMsgInvalidBudget          = "Invalid budget, expected a non-negative amount and budget_period monthly or total"
+	ErrMsgPriceNotFound          = "Price not found"
+	ErrMsgPriceModelRequired     = "Price model_id is required"
+	ErrMsgInvalidPrice           = "Prices cannot be negative"
+	ErrMsgInvalidUsageGroup      = "Invalid group_by dimension '%s', expected day, account, alias, api_key or model"
+	ErrMsgInvalidDateRange       = "Invalid date range, expected from and to as YYYY-MM-DD"
+	ErrMsgAPIKeyNotFound         = "API key not found"
+	ErrMsgAPIKeyNameRequired     = "API key name is required"
+	ErrMsgRequestLogNotFound     = "Request log not found"
+	ErrMsgInvalidLogFilter       = "Invalid %s filter, expected an integer"
 )
@@ -2,9 +2,10 @@ package common
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
 	"os"
 	"strconv"
@@ -48,6 +49,50 @@ func ValidateModelProvider(provider models.Provider) bool {
 	return validProviders[provider]
 }
 
+// ValidateRoutingStrategy validates if the routing strategy is supported
+func ValidateRoutingStrategy(strategy models.RoutingStrategy) bool {
+	validStrategies := map[models.RoutingStrategy]bool{
+		models.StrategyRandom:        true,
+		models.StrategyRoundRobin:    true,
+		models.StrategyWeighted:      true,
+		models.StrategyFailover:      true,
+		models.StrategyLeastLatency:  true,
+		models.StrategyLeastInFlight: true,
+	}
+	return validStrategies[strategy]
+}
+
+// ValidateAccountProtocol validates if the account protocol is supported
+func ValidateAccountProtocol(protocol models.AccountProtocol) bool {
+	validProtocols := map[models.AccountProtocol]bool{
+		models.ProtocolOpenAI:    true,
+		models.ProtocolAnthropic: true,
+		models.ProtocolGemini:    true,
+	}
+	return validProtocols[protocol]
+}
+
+// ValidateBudgetPeriod validates if the account budget period is supported
+func ValidateBudgetPeriod(period models.BudgetPeriod) bool {
+	validPeriods := map[models.BudgetPeriod]bool{
+		models.BudgetPeriodMonthly: true,
+		models.BudgetPeriodTotal:   true,
+	}
+	return validPeriods[period]
+}
+
+// ValidateUsageGroup validates if the usage summary dimension is supported
+func ValidateUsageGroup(group string) bool {
+	validGroups := map[string]bool{
+		models.UsageGroupDay:     true,
+		models.UsageGroupAccount: true,
+		models.UsageGroupAlias:   true,
+		models.UsageGroupAPIKey:  true,
+		models.UsageGroupModel:   true,
+	}
+	return validGroups[group]
+}
+
 // GetEnvOrDefault gets an environment variable or returns a default value
 func GetEnvOrDefault(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -57,6 +102,15 @@ func GetEnvOrDefault(key, defaultValue string) string {
 	return value
 }
 
+// GetEnvIntOrDefault gets an integer environment variable or returns a default value
+func GetEnvIntOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // ExtractModelID extracts model ID from request body
 func ExtractModelID(bodyBytes []byte) string {
 	var requestBody map[string]interface{}
@@ -123,6 +177,48 @@ func GetRandomElement[T any](elements []T) T {
 	return elements[index]
 }
 
+// GenerateAPIKey generates a new random client API key
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, constants.APIKeyRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return constants.APIKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// GetWeightedRandomIndex selects an index with probability proportional to its weight
+// Non-positive weights are treated as 1
+func GetWeightedRandomIndex(weights []int) int {
+	if len(weights) <= 1 {
+		return 0
+	}
+
+	total := 0
+	for _, weight := range weights {
+		if weight <= 0 {
+			weight = 1
+		}
+		total += weight
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(total)))
+	if err != nil {
+		return GetRandomIndex(len(weights))
+	}
+
+	target := int(n.Int64())
+	for i, weight := range weights {
+		if weight <= 0 {
+			weight = 1
+		}
+		if target < weight {
+			return i
+		}
+		target -= weight
+	}
+	return len(weights) - 1
+}
+
 // GetCurrentTimestamp returns the current timestamp in milliseconds
 func GetCurrentTimestamp() int64 {
 	return time.Now().UnixMilli()
@@ -137,6 +233,6 @@ func ResetCounterIfNeeded() {
 	if current >= constants.CounterResetThreshold {
 		n, _ := rand.Int(rand.Reader, big.NewInt(100000))
 		atomic.StoreUint64(counter, 100000+n.Uint64())
-		log.Printf("[Counter] Reset global counter to: %d", 100000+n.Uint64())
+		slog.Info("global counter reset", "value", 100000+n.Uint64())
 	}
 }
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"air_router/constants"
+)
+
+// requestIDPattern restricts request IDs accepted from clients to short, log-safe tokens
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// InitLogger installs the default structured logger, configured by
+// LOG_LEVEL (debug, info, warn, error) and LOG_FORMAT (text, json)
+// The standard log package is routed through it as well
+func InitLogger() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(GetEnvOrDefault("LOG_LEVEL", "info"))); err != nil {
+		level = slog.LevelInfo
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if strings.EqualFold(GetEnvOrDefault("LOG_FORMAT", "text"), "json") {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	}
+	slog.SetDefault(slog.New(&requestIDHandler{Handler: handler}))
+}
+
+// requestIDHandler adds the request ID carried by the context to every record
+// A *gin.Context can be passed as the context of slog's *Context functions
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h *requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestID, ok := ctx.Value(constants.ContextKeyRequestID).(string); ok && requestID != "" {
+			record.AddAttrs(slog.String("request_id", requestID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestIDHandler) WithGroup(name string) slog.Handler {
+	return &requestIDHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// RequestIDOrGenerate returns the client's request ID when it is acceptable, a new random one otherwise
+func RequestIDOrGenerate(requestID string) string {
+	if requestIDPattern.MatchString(requestID) {
+		return requestID
+	}
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrFirstEventTimeout is returned when a stream did not produce its first event in time
+var ErrFirstEventTimeout = errors.New("upstream stream produced no event within the first chunk timeout")
+
+// firstEventRead is the outcome of reading up to the first SSE event
+type firstEventRead struct {
+	data []byte
+	err  error
+}
+
+// WaitForFirstEvent blocks until the response body yields its first SSE data line or the timeout expires
+// Comment lines such as keep-alives do not count. The bytes read are replayed in front of the body,
+// so nothing is lost for the client; on timeout the body is closed and the caller may try another account
+func WaitForFirstEvent(resp *http.Response, timeout time.Duration) error {
+	done := make(chan firstEventRead, 1)
+	go func() {
+		var data []byte
+		buf := make([]byte, 4096)
+		for {
+			n, err := resp.Body.Read(buf)
+			data = append(data, buf[:n]...)
+			if err != nil || bytes.Contains(data, []byte("data:")) {
+				done <- firstEventRead{data: data, err: err}
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case read := <-done:
+		if read.err != nil && read.err != io.EOF {
+			resp.Body.Close()
+			return read.err
+		}
+		resp.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(read.data), resp.Body), Closer: resp.Body}
+		return nil
+	case <-timer.C:
+		// Closing the body unblocks the pending read
+		resp.Body.Close()
+		return ErrFirstEventTimeout
+	}
+}
+
+// prefixedBody replays already consumed bytes before the rest of a body
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
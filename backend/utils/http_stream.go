@@ -2,7 +2,7 @@ package utils
 
 import (
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"air_router/constants"
@@ -10,16 +10,27 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// StreamResponse streams the HTTP response to the client
-func StreamResponse(c *gin.Context, resp *http.Response) error {
-	// Copy response headers
-	for key, values := range resp.Header {
+// CopyResponseHeaders copies upstream response headers to the client response
+// The upstream request ID is renamed so it does not replace the router's own
+func CopyResponseHeaders(c *gin.Context, header http.Header) {
+	for key, values := range header {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(constants.RequestIDHeader) {
+			key = constants.UpstreamRequestIDHeader
+		}
 		for _, value := range values {
 			c.Header(key, value)
 		}
 	}
+}
+
+// StreamResponse streams the HTTP response to the client
+// Returns the token usage reported in the relayed body
+func StreamResponse(c *gin.Context, resp *http.Response) Usage {
+	CopyResponseHeaders(c, resp.Header)
 	c.Status(resp.StatusCode)
 
+	usageParser := NewUsageParser(resp.Header.Get("Content-Type"))
+
 	// Stream with small buffer for real-time response
 	buf := make([]byte, constants.StreamBufferSize)
 	for {
@@ -27,17 +38,18 @@ func StreamResponse(c *gin.Context, resp *http.Response) error {
 		if n > 0 {
 			_, writeErr := c.Writer.Write(buf[:n])
 			if writeErr != nil {
-				log.Printf("Error writing response: %v", writeErr)
+				slog.WarnContext(c, "error writing response", "error", writeErr)
 				break
 			}
 			c.Writer.Flush() // Ensure immediate flush
+			usageParser.Write(buf[:n])
 		}
 		if err != nil {
 			if err != io.EOF {
-				log.Printf("Error reading response: %v", err)
+				slog.WarnContext(c, "error reading upstream response", "error", err)
 			}
 			break
 		}
 	}
-	return nil
+	return usageParser.Usage()
 }
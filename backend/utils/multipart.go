@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+)
+
+// maxMultipartFieldBytes caps the size of a form field value read for routing
+const maxMultipartFieldBytes = 4096
+
+// MultipartBoundary returns the boundary of a multipart/form-data content type
+func MultipartBoundary(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// MultipartFields reads the values of the named form fields of a multipart body
+// Parts are walked one by one, file contents are skipped without being copied
+func MultipartFields(body []byte, boundary string, names ...string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	fields := make(map[string]string)
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() == "" && wanted[part.FormName()] {
+			value, err := io.ReadAll(io.LimitReader(part, maxMultipartFieldBytes))
+			if err != nil {
+				return nil, err
+			}
+			fields[part.FormName()] = string(value)
+		}
+		part.Close()
+	}
+}
+
+// RewriteMultipartField re-encodes a multipart body with the same boundary, replacing the value of one form field
+// Every other part, including its headers and transfer encoding, is copied through unchanged
+func RewriteMultipartField(body []byte, boundary, name, value string) ([]byte, error) {
+	var out bytes.Buffer
+	out.Grow(len(body) + len(value))
+
+	writer := multipart.NewWriter(&out)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() == "" && part.FormName() == name {
+			_, err = io.WriteString(target, value)
+		} else {
+			_, err = io.Copy(target, part)
+		}
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
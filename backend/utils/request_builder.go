@@ -2,16 +2,17 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 
 	"air_router/constants"
 	"air_router/models"
 )
 
-// CreateProxyRequest creates an HTTP request for proxying
+// CreateProxyRequest creates an HTTP request for proxying bound to ctx
 // isClaude indicates whether this is a Claude API request
-func CreateProxyRequest(method, targetURL string, bodyBytes []byte, account models.Account, headers http.Header, isClaude bool) (*http.Request, error) {
-	req, err := http.NewRequest(method, targetURL, bytes.NewReader(bodyBytes))
+func CreateProxyRequest(ctx context.Context, method, targetURL string, bodyBytes []byte, account models.Account, headers http.Header, isClaude bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, err
 	}
@@ -24,10 +25,15 @@ func CreateProxyRequest(method, targetURL string, bodyBytes []byte, account mode
 	}
 
 	// Set API key based on API type
-	if isClaude {
+	if account.Protocol == models.ProtocolGemini {
+		req.Header.Set("x-goog-api-key", account.APIKey)
+		req.Header.Del("Authorization")
+		req.Header.Del("X-Api-Key")
+	} else if isClaude {
 		req.Header.Set("X-Api-Key", account.APIKey)
 		// Remove Authorization header if present
 		req.Header.Del("Authorization")
+		req.Header.Del("x-goog-api-key")
 		// Set or get anthropic-version header
 		anthropicVersion := headers.Get("anthropic-version")
 		if anthropicVersion == "" {
@@ -36,6 +42,9 @@ func CreateProxyRequest(method, targetURL string, bodyBytes []byte, account mode
 		req.Header.Set("anthropic-version", anthropicVersion)
 	} else {
 		req.Header.Set("Authorization", "Bearer "+account.APIKey)
+		// Never leak the client's router key to the upstream
+		req.Header.Del("X-Api-Key")
+		req.Header.Del("x-goog-api-key")
 	}
 
 	// Always set User-Agent
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"air_router/models"
+	"air_router/utils/common"
+)
+
+// Timeouts bounds the phases of one upstream request
+type Timeouts struct {
+	Connect   time.Duration // Establishing the TCP/TLS connection
+	FirstByte time.Duration // From sending the request to receiving response headers
+	Idle      time.Duration // Maximum silence between two body reads
+}
+
+// ErrFirstByteTimeout is returned when response headers did not arrive in time
+var ErrFirstByteTimeout = errors.New("upstream did not respond within the first byte timeout")
+
+// DefaultTimeouts is used for every timeout an account leaves at 0
+var DefaultTimeouts = Timeouts{
+	Connect:   time.Duration(common.GetEnvIntOrDefault("UPSTREAM_CONNECT_TIMEOUT_MS", 10000)) * time.Millisecond,
+	FirstByte: time.Duration(common.GetEnvIntOrDefault("UPSTREAM_FIRST_BYTE_TIMEOUT_MS", 300000)) * time.Millisecond,
+	Idle:      time.Duration(common.GetEnvIntOrDefault("UPSTREAM_IDLE_TIMEOUT_MS", 300000)) * time.Millisecond,
+}
+
+// AccountTimeouts returns the timeouts of an account, falling back to the defaults
+func AccountTimeouts(account models.Account) Timeouts {
+	timeouts := DefaultTimeouts
+	if account.ConnectTimeoutMs > 0 {
+		timeouts.Connect = time.Duration(account.ConnectTimeoutMs) * time.Millisecond
+	}
+	if account.FirstByteTimeoutMs > 0 {
+		timeouts.FirstByte = time.Duration(account.FirstByteTimeoutMs) * time.Millisecond
+	}
+	if account.IdleTimeoutMs > 0 {
+		timeouts.Idle = time.Duration(account.IdleTimeoutMs) * time.Millisecond
+	}
+	return timeouts
+}
+
+// clientsByConnectTimeout caches one client per connect timeout so connections are still pooled
+var clientsByConnectTimeout sync.Map // time.Duration -> *http.Client
+
+// ClientForTimeouts returns an HTTP client whose dialer honors the connect timeout
+func ClientForTimeouts(timeouts Timeouts) *http.Client {
+	if client, exists := clientsByConnectTimeout.Load(timeouts.Connect); exists {
+		return client.(*http.Client)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeouts.Connect,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: SkipTLSVerify,
+		},
+	}
+	client, _ := clientsByConnectTimeout.LoadOrStore(timeouts.Connect, &http.Client{
+		Transport: transport,
+		Timeout:   0, // Phases are bounded through the request context instead
+	})
+	return client.(*http.Client)
+}
+
+// DoWithTimeouts sends a request whose context was created with the returned cancel function
+// The request is canceled when response headers take longer than the first byte timeout;
+// the response body then cancels it after the idle timeout and releases it on Close
+func DoWithTimeouts(client *http.Client, req *http.Request, cancel context.CancelFunc, timeouts Timeouts) (*http.Response, error) {
+	var firstByteTimer *time.Timer
+	if timeouts.FirstByte > 0 {
+		firstByteTimer = time.AfterFunc(timeouts.FirstByte, cancel)
+	}
+
+	resp, err := client.Do(req)
+	if firstByteTimer != nil && !firstByteTimer.Stop() {
+		// The timer fired, so the request context is already canceled
+		if err == nil {
+			resp.Body.Close()
+		}
+		return nil, ErrFirstByteTimeout
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = newIdleTimeoutBody(resp.Body, timeouts.Idle, cancel)
+	return resp, nil
+}
+
+// idleTimeoutBody cancels the upstream request when no data arrives for too long
+type idleTimeoutBody struct {
+	body   io.ReadCloser
+	idle   time.Duration
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, idle time.Duration, cancel context.CancelFunc) *idleTimeoutBody {
+	b := &idleTimeoutBody{body: body, idle: idle, cancel: cancel}
+	if idle > 0 {
+		b.timer = time.AfterFunc(idle, cancel)
+	}
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if b.timer != nil && n > 0 {
+		b.timer.Reset(b.idle)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
@@ -21,9 +21,13 @@ func BuildTargetURL(account models.Account, path string) string {
 		return baseURL + "/" + path
 	}
 
-	// BaseURL is just domain/ip:port, append /v1
+	// BaseURL is just domain/ip:port, append the API version: /v1, or /v1beta for Gemini
+	version := "/v1"
+	if account.Protocol == models.ProtocolGemini {
+		version = "/v1beta"
+	}
 	if strings.HasPrefix(path, "/") {
-		return baseURL + "/v1" + path
+		return baseURL + version + path
 	}
-	return baseURL + "/v1/" + path
+	return baseURL + version + "/" + path
 }
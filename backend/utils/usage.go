@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"air_router/constants"
+)
+
+// Usage represents the token usage reported by an upstream response
+// CachedTokens is the part of PromptTokens served from the upstream prompt cache
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	CachedTokens     int64 `json:"cached_tokens"`
+}
+
+// TotalTokens returns the sum of prompt and completion tokens
+func (u Usage) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// merge keeps the largest value of each field
+// Streams report usage cumulatively (Anthropic sends input tokens first and output tokens last)
+func (u *Usage) merge(other Usage) {
+	if other.PromptTokens > u.PromptTokens {
+		u.PromptTokens = other.PromptTokens
+	}
+	if other.CompletionTokens > u.CompletionTokens {
+		u.CompletionTokens = other.CompletionTokens
+	}
+	if other.CachedTokens > u.CachedTokens {
+		u.CachedTokens = other.CachedTokens
+	}
+}
+
+// rawUsage covers the usage object formats of OpenAI Chat Completions, Responses, Anthropic Messages and Gemini
+type rawUsage struct {
+	PromptTokens             int64           `json:"prompt_tokens"`
+	CompletionTokens         int64           `json:"completion_tokens"`
+	PromptTokensDetails      rawCachedTokens `json:"prompt_tokens_details"`
+	InputTokens              int64           `json:"input_tokens"`
+	OutputTokens             int64           `json:"output_tokens"`
+	InputTokensDetails       rawCachedTokens `json:"input_tokens_details"` // Responses API
+	CacheCreationInputTokens int64           `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64           `json:"cache_read_input_tokens"`
+	PromptTokenCount         int64           `json:"promptTokenCount"`
+	CandidatesTokenCount     int64           `json:"candidatesTokenCount"`
+	ThoughtsTokenCount       int64           `json:"thoughtsTokenCount"`
+	CachedContentTokenCount  int64           `json:"cachedContentTokenCount"`
+}
+
+// rawCachedTokens is the OpenAI breakdown of prompt tokens
+type rawCachedTokens struct {
+	CachedTokens int64 `json:"cached_tokens"`
+}
+
+// toUsage normalizes a raw usage object
+func (r rawUsage) toUsage() Usage {
+	usage := Usage{
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		CachedTokens:     r.PromptTokensDetails.CachedTokens,
+	}
+	if r.InputTokens > 0 || r.OutputTokens > 0 {
+		usage.PromptTokens = r.InputTokens
+		usage.CompletionTokens = r.OutputTokens
+		usage.CachedTokens = r.InputTokensDetails.CachedTokens
+	}
+	if r.CacheCreationInputTokens > 0 || r.CacheReadInputTokens > 0 {
+		// Anthropic reports cache reads and writes outside input_tokens
+		usage.PromptTokens = r.InputTokens + r.CacheCreationInputTokens + r.CacheReadInputTokens
+		usage.CompletionTokens = r.OutputTokens
+		usage.CachedTokens = r.CacheReadInputTokens
+	}
+	if r.PromptTokenCount > 0 || r.CandidatesTokenCount > 0 {
+		// Gemini bills thinking tokens as output but reports them apart
+		usage.PromptTokens = r.PromptTokenCount
+		usage.CompletionTokens = r.CandidatesTokenCount + r.ThoughtsTokenCount
+		usage.CachedTokens = r.CachedContentTokenCount
+	}
+	return usage
+}
+
+// UsageParser extracts token usage from a response body while it is relayed to the client
+// It understands plain JSON bodies as well as SSE streams
+type UsageParser struct {
+	isSSE    bool
+	isJSON   bool
+	buf      bytes.Buffer
+	overflow bool
+	usage    Usage
+}
+
+// NewUsageParser creates a UsageParser for a response with the given Content-Type
+func NewUsageParser(contentType string) *UsageParser {
+	contentType = strings.ToLower(contentType)
+	return &UsageParser{
+		isSSE:  strings.Contains(contentType, "text/event-stream"),
+		isJSON: strings.Contains(contentType, "json"),
+	}
+}
+
+// Write feeds relayed response bytes into the parser
+func (p *UsageParser) Write(data []byte) (int, error) {
+	if p.isSSE {
+		p.buf.Write(data)
+		p.consumeLines()
+		return len(data), nil
+	}
+
+	if !p.isJSON || p.overflow {
+		return len(data), nil
+	}
+
+	// Bodies beyond the capture limit are not parsed
+	if p.buf.Len()+len(data) > constants.MaxUsageCaptureBytes {
+		p.overflow = true
+		p.buf.Reset()
+		return len(data), nil
+	}
+	p.buf.Write(data)
+	return len(data), nil
+}
+
+// Usage finishes parsing and returns the collected usage
+func (p *UsageParser) Usage() Usage {
+	if p.isSSE {
+		// Handle a trailing event without a final newline
+		p.parseLine(p.buf.Bytes())
+		p.buf.Reset()
+	} else if p.isJSON && !p.overflow {
+		p.parsePayload(p.buf.Bytes())
+		p.buf.Reset()
+	}
+	return p.usage
+}
+
+// consumeLines parses every complete SSE line in the buffer
+func (p *UsageParser) consumeLines() {
+	for {
+		data := p.buf.Bytes()
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			// Drop a runaway line instead of growing without bound
+			if p.buf.Len() > constants.MaxUsageCaptureBytes {
+				p.buf.Reset()
+			}
+			return
+		}
+		p.parseLine(data[:idx])
+		p.buf.Next(idx + 1)
+	}
+}
+
+// parseLine parses a single SSE "data:" line
+func (p *UsageParser) parseLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	payload := bytes.TrimSpace(line[len("data:"):])
+	if len(payload) == 0 || payload[0] != '{' {
+		return
+	}
+	p.parsePayload(payload)
+}
+
+// parsePayload extracts usage from a JSON object
+// Usage may live at the top level, under "message" (Anthropic message_start) or under "response" (Responses API events);
+// Gemini reports it as "usageMetadata", and its non-SSE streams are a JSON array of responses
+func (p *UsageParser) parsePayload(payload []byte) {
+	if !bytes.Contains(payload, []byte(`"usage`)) {
+		return
+	}
+
+	if payload = bytes.TrimSpace(payload); len(payload) > 0 && payload[0] == '[' {
+		var objects []json.RawMessage
+		if err := json.Unmarshal(payload, &objects); err == nil {
+			for _, object := range objects {
+				p.parsePayload(object)
+			}
+		}
+		return
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return
+	}
+
+	p.mergeRawUsage(object["usage"])
+	p.mergeRawUsage(object["usageMetadata"])
+	for _, key := range []string{"message", "response"} {
+		nested, ok := object[key]
+		if !ok {
+			continue
+		}
+		var nestedObject map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &nestedObject); err == nil {
+			p.mergeRawUsage(nestedObject["usage"])
+		}
+	}
+}
+
+// mergeRawUsage decodes a raw usage object and merges it into the collected usage
+func (p *UsageParser) mergeRawUsage(data json.RawMessage) {
+	if len(data) == 0 {
+		return
+	}
+	var raw rawUsage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	p.usage.merge(raw.toUsage())
+}
@@ -57,6 +57,7 @@ const enUS = {
   apiKey: "API Key",
   apiKeyPlaceholder: "sk-...",
   claudeAvailable: "Claude Available",
+  responsesAvailable: "Responses API Available",
   extInfo: "Extended Info",
   extInfoPlaceholder: "Optional extended information",
   selectAll: "Select All",
@@ -72,6 +73,7 @@ const enUS = {
   // Status
   active: "Active",
   inactive: "Inactive",
+  paused: "Paused",
   enabled: "Enabled",
   disabled: "Disabled",
   unknown: "Unknown",
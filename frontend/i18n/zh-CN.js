@@ -57,6 +57,7 @@ const zhCN = {
   apiKey: "API密钥",
   apiKeyPlaceholder: "sk-...",
   claudeAvailable: "Claude可用",
+  responsesAvailable: "Responses可用",
   extInfo: "扩展信息",
   extInfoPlaceholder: "可选的扩展信息",
   selectAll: "全选",
@@ -72,6 +73,7 @@ const zhCN = {
   // Status
   active: "活跃",
   inactive: "未激活",
+  paused: "已暂停",
   enabled: "已启用",
   disabled: "已禁用",
   unknown: "Unknown",
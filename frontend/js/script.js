@@ -440,6 +440,7 @@ function createAccountCard(account) {
     const name = escapeHtml(account.name || window.i18n.t('unknown'));
     const baseUrl = escapeHtml(account.base_url || '');
     const enabled = Boolean(account.enabled);
+    const pausedReason = escapeHtml(account.paused_reason || '');
     const claudeAvailable = Boolean(account.claude_available);
     const accountId = parseInt(account.id) || 0;
     const updatedAt = formatTimestamp(account.updated_at || 0);
@@ -447,6 +448,7 @@ function createAccountCard(account) {
     // Get translations
     const activeText = window.i18n.t('active');
     const inactiveText = window.i18n.t('inactive');
+    const pausedText = window.i18n.t('paused');
     const baseURLLabel = window.i18n.t('baseURL');
     const apiKeyLabel = window.i18n.t('apiKey');
     const lastUpdatedLabel = window.i18n.t('lastUpdated');
@@ -461,9 +463,9 @@ function createAccountCard(account) {
         <div class="account-card">
             <div class="card-header">
                 <h3 class="card-title">${name}${claudeAvailable ? `<span class="claude-badge">${claudeBadgeText}</span>` : ''}</h3>
-                <span class="card-status ${enabled ? 'enabled' : 'disabled'}">
+                ${enabled && pausedReason ? `<span class="card-status paused" title="${pausedReason}">${pausedText}</span>` : `<span class="card-status ${enabled ? 'enabled' : 'disabled'}">
                     ${enabled ? activeText : inactiveText}
-                </span>
+                </span>`}
             </div>
             <div class="card-body">
                 <div class="card-field clickable">
@@ -630,6 +632,7 @@ function handleFormSubmit(event) {
         base_url: document.getElementById('base_url').value.trim(),
         api_key: document.getElementById('api_key').value.trim(),
         claude_available: document.getElementById('claude_available').checked,
+        responses_available: document.getElementById('responses_available').checked,
         ext: document.getElementById('ext').value,
         enabled: true // Default to enabled
     };
@@ -686,6 +689,7 @@ function editAccount(id) {
             document.getElementById('base_url').value = account.base_url;
             document.getElementById('api_key').value = account.api_key;
             document.getElementById('claude_available').checked = account.claude_available || false;
+            document.getElementById('responses_available').checked = account.responses_available || false;
             document.getElementById('ext').value = account.ext || '';
 
             // Set editing mode